@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/paconte/gocrawler/crawler"
 
@@ -15,6 +20,34 @@ var (
 	url      string
 	ms       int
 	reqs     int
+	depth    int
+	branch   int
+	workers  int
+	rate     float64
+	delay    int
+	conns    int
+	noRobots bool
+	retries  int
+	backoff  int
+	maxDelay int
+	maxBody  int64
+	probe    bool
+	cacheDir string
+	scope    string
+	hosts    []string
+	sortQs   bool
+	noTrack  bool
+	slash    string
+	kinds    []string
+	recordNf bool
+	includes []string
+	excludes []string
+	prefix   string
+	sitemaps string
+	edges    bool
+	sorted   bool
+	scorer   string
+	keywords []string
 	// rootCmd represents the base command when called without any subcommands.
 	rootCmd = NewRootCmd()
 )
@@ -26,20 +59,90 @@ func NewRootCmd() *cobra.Command {
 		Short: "Crawler generates a tree of subdomains for a given domain.",
 		Long:  `A crawler that support different algorithms when searching for subdomains of a web site.`,
 		Args:  cobra.MatchAll(cobra.MaximumNArgs(0)),
+		// The errors are printed once by Execute, without the usage
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateStrategy()
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			runCrawler()
 		},
 	}
+	cmd.AddCommand(newStrategiesCmd())
 
 	// Define command flags
-	cmd.PersistentFlags().StringVarP(&strategy, "strategy", "s", "", "The algorithm used for search, either OneLevel or Recursive")
+	cmd.PersistentFlags().StringVarP(&strategy, "strategy", "s", "", "The algorithm used for search, see the strategies command")
 	cmd.PersistentFlags().StringVarP(&url, "url", "u", "", "The url to search for subdomains")
 	cmd.PersistentFlags().IntVarP(&ms, "milliseconds", "m", 0, "The ms to limit the search")
 	cmd.PersistentFlags().IntVarP(&reqs, "requests", "r", 0, "The requests to limit the search")
+	cmd.PersistentFlags().IntVar(&depth, "depth", 0, "The maximum distance in links from the url, 0 for no limit")
+	cmd.PersistentFlags().IntVar(&branch, "branch-pages", 0, "The maximum pages crawled per branch by the DepthFirst strategies, 0 for no limit")
+	cmd.PersistentFlags().IntVarP(&workers, "workers", "w", crawler.DefaultWorkers, "The number of concurrent workers of the parallel strategies")
+	cmd.PersistentFlags().Float64Var(&rate, "rate", 0, "The maximum requests per second to a host, 0 for no limit")
+	cmd.PersistentFlags().IntVar(&delay, "delay", 0, "The minimum ms between two requests to a host")
+	cmd.PersistentFlags().IntVar(&conns, "host-conns", 0, "The maximum concurrent requests to a host, 0 for no limit")
+	cmd.PersistentFlags().BoolVar(&noRobots, "ignore-robots", false, "Ignore the robots.txt rules of the crawled hosts")
+	cmd.PersistentFlags().IntVar(&retries, "retries", 0, "The maximum retries of a failed request")
+	cmd.PersistentFlags().IntVar(&backoff, "retry-delay", 500, "The ms to wait before the first retry, doubled on every retry")
+	cmd.PersistentFlags().IntVar(&maxDelay, "retry-max-delay", 30*1000, "The maximum ms to wait between two retries")
+	cmd.PersistentFlags().Int64Var(&maxBody, "max-body-size", crawler.DefaultMaxBodySize, "The maximum bytes read from a response body")
+	cmd.PersistentFlags().BoolVar(&probe, "probe", false, "Send a HEAD request first and skip the downloads of non HTML content")
+	cmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "The directory of the HTTP cache revalidated on later crawls, none by default")
+
+	cmd.PersistentFlags().StringVar(&scope, "scope", "exact", "The hosts to crawl: exact for the host of the url, domain for its registrable domain or hosts for the allowed hosts")
+	cmd.PersistentFlags().StringSliceVar(&hosts, "host", nil, "A host allowed by the hosts scope, can be repeated")
+
+	cmd.PersistentFlags().BoolVar(&sortQs, "sort-query", false, "Sort the query parameters of the URLs before deduplicating them")
+	cmd.PersistentFlags().BoolVar(&noTrack, "strip-tracking", false, "Remove the tracking query parameters, such as utm_source, from the URLs")
+	cmd.PersistentFlags().StringVar(&slash, "trailing-slash", "keep", "The trailing slash policy of the URL paths: keep, add or remove")
+
+	cmd.PersistentFlags().StringSliceVar(&kinds, "links", crawler.DefaultLinkKinds, "The elements whose links are crawled: a, area, link, iframe, frame, form, img, script, source or meta")
+
+	cmd.PersistentFlags().BoolVar(&recordNf, "record-nofollow", false, "Report the targets of the nofollow links without crawling them")
+
+	cmd.PersistentFlags().StringArrayVar(&includes, "include", nil, "A glob, or a regex prefixed with re:, of the URLs to crawl, can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "A glob, or a regex prefixed with re:, of the URLs not to crawl, can be repeated")
+	cmd.PersistentFlags().StringVar(&prefix, "path-prefix", "", "The path prefix of the URLs to crawl")
+
+	cmd.PersistentFlags().StringVar(&sitemaps, "sitemaps", "none", "Crawl the URLs of the sitemaps: none, links for sitemaps plus links or only for sitemaps only")
+
+	cmd.PersistentFlags().BoolVar(&edges, "edges", false, "Print the links found on every page with their anchor text and attributes")
+
+	cmd.PersistentFlags().StringVar(&scorer, "scorer", "inbound", "The ordering of the BestFirst strategies: depth, inbound or keyword")
+	cmd.PersistentFlags().StringSliceVar(&keywords, "keyword", nil, "A keyword of the URLs or anchor texts crawled first by the keyword scorer, can be repeated")
+
+	cmd.PersistentFlags().BoolVar(&sorted, "sort", false, "Print the results sorted by URL once the crawl is complete instead of as they arrive")
 
 	return cmd
 }
 
+// newStrategiesCmd creates the command listing the registered strategies.
+func newStrategiesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "strategies",
+		Short: "List the strategies available to the crawler.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, info := range crawler.Strategies() {
+				fmt.Printf("%s\t%s\n", info.Name, info.Description)
+			}
+		},
+	}
+}
+
+// validateStrategy checks that the strategy flag names a registered strategy.
+func validateStrategy() error {
+	if _, ok := crawler.LookupStrategy(strategy); ok {
+		return nil
+	}
+	names := []string{}
+	for _, info := range crawler.Strategies() {
+		names = append(names, info.Name)
+	}
+	return fmt.Errorf("unknown strategy %q, expected one of: %s", strategy, strings.Join(names, ", "))
+}
+
 // Execute executes the root command.
 func Execute(cmd *cobra.Command) {
 	if err := rootCmd.Execute(); err != nil {
@@ -49,14 +152,266 @@ func Execute(cmd *cobra.Command) {
 }
 
 // runCrawler runs the web crawler using the specified strategy and URL.
+// An interrupt signal stops the crawl and prints the results of the URLs visited so far.
 func runCrawler() {
-	res, err := crawler.Run(url, strategy, crawler.Limits{Milliseconds: ms, Requests: reqs})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	crawlScope, err := newScope()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	canonicalizer, err := newCanonicalizer()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	extractor, err := crawler.NewHTMLLinkExtractor(kinds...)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	sitemapMode, err := crawler.ParseSitemapMode(sitemaps)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	urlScorer, err := crawler.ParseScorer(scorer, keywords)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The robots.txt files are fetched with the same settings, but without the
+	// Crawl-delay, which is read from them
+	var robots *crawler.Robots
+	if !noRobots {
+		robotsFetcher, err := newFetcher(nil)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		robots = crawler.NewRobots(robotsFetcher, crawler.DefaultUserAgent)
+	}
+	fetcher, err := newFetcher(robots)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	opts := crawler.Options{
+		Fetcher:        fetcher,
+		Limits:         crawler.Limits{Milliseconds: ms, Requests: reqs, MaxDepth: depth, BranchPages: branch},
+		Workers:        workers,
+		Robots:         robots,
+		MaxBodySize:    maxBody,
+		Scope:          crawlScope,
+		Canonicalizer:  canonicalizer,
+		LinkExtractor:  extractor,
+		RecordNoFollow: recordNf,
+		Sitemaps:       sitemapMode,
+		Scorer:         urlScorer,
+	}
+	res, err := crawl(ctx, opts)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	for _, link := range res {
-		fmt.Println(link)
+	printRedirects(res)
+	printNoIndex(res)
+	printCanonical(res)
+	if edges {
+		printEdges(res)
+	}
+}
+
+// crawl runs the crawler and prints the results of the pages that can be
+// indexed, as they arrive or, with the sort flag, sorted once the crawl is
+// complete. It returns the results merged under their canonical URL.
+func crawl(ctx context.Context, opts crawler.Options) ([]crawler.PageResult, error) {
+	if sorted {
+		res, err := crawler.Run(ctx, url, strategy, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range res {
+			if !result.NoIndex {
+				printResult(result)
+			}
+		}
+		return res, nil
+	}
+
+	results, err := crawler.Stream(ctx, url, strategy, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := []crawler.PageResult{}
+	for result := range results {
+		if !result.NoIndex {
+			printResult(result)
+		}
+		res = append(res, result)
+	}
+	return crawler.MergeCanonical(res, opts.Canonicalizer), nil
+}
+
+// printResult prints a crawl result as a line of tab separated columns: URL,
+// status, content type, size, duration, attempts, depth, parent and error.
+// The targets of the nofollow links, which are not fetched, are reported with a
+// nofollow error.
+func printResult(result crawler.PageResult) {
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	} else if result.NoFollow {
+		errMsg = "nofollow"
+	}
+	fmt.Printf("%s\t%d\t%s\t%d\t%s\t%d\t%d\t%s\t%s\n",
+		result.URL, result.Status, result.ContentType, result.Size, result.Duration,
+		result.Attempts, result.Depth, result.Parent, errMsg)
+}
+
+// printRedirects prints a report of the redirected URLs with their redirect chains.
+func printRedirects(res []crawler.PageResult) {
+	header := false
+	for _, result := range res {
+		if len(result.Redirects) == 0 {
+			continue
+		}
+		if !header {
+			fmt.Println("\nRedirects:")
+			header = true
+		}
+		if result.Err != nil {
+			fmt.Printf("%s -> %s (%v)\n", result.URL, strings.Join(result.Redirects, " -> "), result.Err)
+			continue
+		}
+		fmt.Printf("%s -> %s\n", result.URL, strings.Join(result.Redirects, " -> "))
+	}
+}
+
+// printNoIndex prints a report of the crawled pages asking not to be indexed.
+func printNoIndex(res []crawler.PageResult) {
+	header := false
+	for _, result := range res {
+		if !result.NoIndex {
+			continue
+		}
+		if !header {
+			fmt.Println("\nNoindex:")
+			header = true
+		}
+		printResult(result)
+	}
+}
+
+// printCanonical prints a report of the URLs merged under their canonical URL,
+// and of the pages whose canonical URL is out of scope or not 200 OK.
+func printCanonical(res []crawler.PageResult) {
+	header := false
+	for _, result := range res {
+		if len(result.Duplicates) == 0 && result.CanonicalErr == nil {
+			continue
+		}
+		if !header {
+			fmt.Println("\nCanonical:")
+			header = true
+		}
+		if len(result.Duplicates) > 0 {
+			fmt.Printf("%s <- %s\n", result.URL, strings.Join(result.Duplicates, ", "))
+		}
+		if result.CanonicalErr != nil {
+			fmt.Printf("%s -> %s\t%s\n", result.URL, result.Canonical, result.CanonicalErr)
+		}
+	}
+}
+
+// printEdges prints the links found on the crawled pages as lines of tab
+// separated columns: source, target, tag, attribute, rel, title and anchor text.
+func printEdges(res []crawler.PageResult) {
+	fmt.Println("\nEdges:")
+	for _, result := range res {
+		for _, link := range result.Links {
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				link.Source, link.URL, link.Tag, link.Attr, link.Rel, link.Title, link.Text)
+		}
+	}
+}
+
+// newScope creates the scope of the crawl according to the flags.
+func newScope() (crawler.Scope, error) {
+	mode, err := crawler.ParseHostMode(scope)
+	if err != nil {
+		return crawler.Scope{}, err
+	}
+	include, err := parsePatterns(includes)
+	if err != nil {
+		return crawler.Scope{}, err
+	}
+	exclude, err := parsePatterns(excludes)
+	if err != nil {
+		return crawler.Scope{}, err
+	}
+	return crawler.Scope{Mode: mode, Hosts: hosts, Include: include, Exclude: exclude, PathPrefix: prefix}, nil
+}
+
+// parsePatterns parses the expressions of the include or exclude patterns.
+func parsePatterns(exprs []string) ([]*crawler.Pattern, error) {
+	patterns := []*crawler.Pattern{}
+	for _, expr := range exprs {
+		pattern, err := crawler.ParsePattern(expr)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// newCanonicalizer creates the canonicalizer of the extracted URLs according to the flags.
+func newCanonicalizer() (crawler.Canonicalizer, error) {
+	policy, err := crawler.ParseTrailingSlash(slash)
+	if err != nil {
+		return crawler.Canonicalizer{}, err
+	}
+	canonicalizer := crawler.Canonicalizer{SortQuery: sortQs, TrailingSlash: policy}
+	if noTrack {
+		canonicalizer.StripParams = crawler.TrackingParams
+	}
+	return canonicalizer, nil
+}
+
+// newFetcher creates the fetcher used by the crawler according to the flags.
+// The Crawl-delay of the robots rules is honored unless robots is nil.
+// Every attempt of a retried request is throttled by the politeness settings.
+func newFetcher(robots *crawler.Robots) (crawler.Fetcher, error) {
+	politeness := crawler.Politeness{
+		RequestsPerSecond: rate,
+		Delay:             time.Duration(delay) * time.Millisecond,
+		MaxConnsPerHost:   conns,
+	}
+	if robots != nil {
+		politeness.CrawlDelay = robots.CrawlDelay
+	}
+	policy := crawler.RetryPolicy{
+		MaxAttempts: retries + 1,
+		BaseDelay:   time.Duration(backoff) * time.Millisecond,
+		MaxDelay:    time.Duration(maxDelay) * time.Millisecond,
+	}
+	client := &http.Client{}
+	if cacheDir != "" {
+		transport, err := crawler.NewCacheTransport(cacheDir, nil)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
 	}
+	fetcher := crawler.NewHTTPFetcher(client)
+	fetcher.Probe = probe
+	return crawler.NewRetryFetcher(crawler.NewPoliteFetcher(fetcher, politeness), policy), nil
 }
@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// CacheTransport is an http.RoundTripper storing the responses on disk and
+// revalidating them with conditional requests.
+// The bodies of the GET responses carrying an ETag or a Last-Modified header
+// are stored once fully read. Later requests to the same URL send the
+// If-None-Match and If-Modified-Since headers, and a 304 Not Modified response
+// is replaced with the cached response.
+type CacheTransport struct {
+	dir       string
+	transport http.RoundTripper
+}
+
+// cacheEntry represents the metadata of a cached response.
+type cacheEntry struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+}
+
+// NewCacheTransport creates a new CacheTransport storing the responses in dir
+// and sending the requests with the given transport.
+// If transport is nil, http.DefaultTransport is used.
+func NewCacheTransport(dir string, transport http.RoundTripper) (*CacheTransport, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &CacheTransport{dir: dir, transport: transport}, nil
+}
+
+// RoundTrip sends the request, revalidating the cached response of its URL if any.
+func (c *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.transport.RoundTrip(req)
+	}
+
+	key := c.key(req.URL.String())
+	entry, cached := c.load(key)
+	if cached {
+		req = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && resp.StatusCode == http.StatusNotModified {
+		body, err := os.Open(filepath.Join(c.dir, key+".body"))
+		if err == nil {
+			resp.Body.Close()
+			return &http.Response{
+				Status:        http.StatusText(entry.Status),
+				StatusCode:    entry.Status,
+				Proto:         resp.Proto,
+				ProtoMajor:    resp.ProtoMajor,
+				ProtoMinor:    resp.ProtoMinor,
+				Header:        entry.Header,
+				Body:          body,
+				ContentLength: -1,
+				Request:       req,
+			}, nil
+		}
+	}
+
+	if resp.StatusCode == http.StatusOK &&
+		(resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		resp.Body = &cacheWriter{
+			ReadCloser: resp.Body,
+			cache:      c,
+			key:        key,
+			entry:      cacheEntry{URL: req.URL.String(), Status: resp.StatusCode, Header: resp.Header},
+		}
+	}
+	return resp, nil
+}
+
+// key returns the file name prefix of the cache entry of a URL.
+func (c *CacheTransport) key(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads the metadata of a cache entry.
+func (c *CacheTransport) load(key string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// store writes a cache entry. The body is written first and renamed into
+// place so that a concurrent reader never sees a partial entry.
+func (c *CacheTransport) store(key string, entry cacheEntry, body []byte) error {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{key + ".body", body}, {key + ".json", meta}} {
+		tmp, err := os.CreateTemp(c.dir, file.name+".*")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(file.data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), filepath.Join(c.dir, file.name))
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheWriter is a response body copying the data read into a buffer and
+// storing it in the cache when the end of the body is reached. Bodies that
+// are not fully read are not stored.
+type cacheWriter struct {
+	io.ReadCloser
+	cache  *CacheTransport
+	key    string
+	entry  cacheEntry
+	buffer bytes.Buffer
+	stored bool
+}
+
+// Read reads from the response body and stores the entry at the end of it.
+func (w *cacheWriter) Read(p []byte) (int, error) {
+	n, err := w.ReadCloser.Read(p)
+	w.buffer.Write(p[:n])
+	if err == io.EOF && !w.stored {
+		w.stored = true
+		w.cache.store(w.key, w.entry, w.buffer.Bytes())
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+package crawler_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NewCacheServer returns a server answering with the given content and the
+// given validator header, honoring the conditional requests. It counts the
+// full responses and the 304 responses.
+func NewCacheServer(content string, header string, value string, full *int, notModified *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if value != "" && (r.Header.Get("If-None-Match") == value || r.Header.Get("If-Modified-Since") == value) {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*full++
+		if header != "" {
+			w.Header().Set(header, value)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(content))
+	}))
+}
+
+func TestCacheTransport(t *testing.T) {
+	content := LoadFileAsString(t, "testdata/treeLevel1.html")
+	tests := []struct {
+		header      string
+		value       string
+		full        int
+		notModified int
+	}{
+		{"ETag", `"v1"`, 1, 2},
+		{"Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT", 1, 2},
+		{"", "", 3, 0},
+	}
+
+	for _, tt := range tests {
+		full, notModified := 0, 0
+		server := NewCacheServer(content, tt.header, tt.value, &full, &notModified)
+
+		transport, err := crawler.NewCacheTransport(t.TempDir(), nil)
+		assert.Nil(t, err)
+		fetcher := crawler.NewHTTPFetcher(&http.Client{Transport: transport})
+		for i := 0; i < 3; i++ {
+			resp, err := fetcher.Fetch(context.Background(), server.URL)
+			assert.Nil(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, "text/html", resp.Header.Get("Content-Type"))
+			data, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, content, string(data))
+		}
+		assert.Equal(t, tt.full, full)
+		assert.Equal(t, tt.notModified, notModified)
+		server.Close()
+	}
+}
+
+func TestCacheTransportPartialBody(t *testing.T) {
+	full, notModified := 0, 0
+	server := NewCacheServer("content", "ETag", `"v1"`, &full, &notModified)
+	defer server.Close()
+
+	transport, _ := crawler.NewCacheTransport(t.TempDir(), nil)
+	fetcher := crawler.NewHTTPFetcher(&http.Client{Transport: transport})
+
+	// A body that is not fully read is not stored
+	resp, _ := fetcher.Fetch(context.Background(), server.URL)
+	io.ReadFull(resp.Body, make([]byte, 2))
+	resp.Body.Close()
+	resp, _ = fetcher.Fetch(context.Background(), server.URL)
+	resp.Body.Close()
+	assert.Equal(t, 2, full)
+	assert.Equal(t, 0, notModified)
+}
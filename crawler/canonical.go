@@ -0,0 +1,225 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TrailingSlash selects how the trailing slash of a URL path is canonicalized.
+type TrailingSlash int
+
+const (
+	// KeepTrailingSlash leaves the trailing slash of the paths as it is.
+	KeepTrailingSlash TrailingSlash = iota
+	// AddTrailingSlash adds a trailing slash to the paths without one.
+	AddTrailingSlash
+	// RemoveTrailingSlash removes the trailing slash of the paths other than "/".
+	RemoveTrailingSlash
+)
+
+// trailingSlashes maps the names of the trailing slash policies, as used by the
+// CLI, to their values.
+var trailingSlashes = map[string]TrailingSlash{
+	"keep":   KeepTrailingSlash,
+	"add":    AddTrailingSlash,
+	"remove": RemoveTrailingSlash,
+}
+
+// ParseTrailingSlash returns the trailing slash policy with the given name:
+// "keep", "add" or "remove".
+func ParseTrailingSlash(name string) (TrailingSlash, error) {
+	policy, ok := trailingSlashes[name]
+	if !ok {
+		return KeepTrailingSlash, fmt.Errorf("unknown trailing slash policy %q", name)
+	}
+	return policy, nil
+}
+
+// TrackingParams are the query parameters commonly added to the links to track
+// the visitors, which do not change the content of the pages.
+var TrackingParams = []string{
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+	"gclid", "fbclid", "msclkid",
+}
+
+// defaultPorts maps the schemes to their default ports.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalizer rewrites the URLs in a canonical form, so that the different
+// spellings of the same URL are crawled once.
+// The zero value lowercases the scheme and host, drops the default port and
+// the fragment, normalizes the percent-encoding and removes the dot segments
+// of the path.
+type Canonicalizer struct {
+	SortQuery     bool          // Sort the query parameters by name
+	StripParams   []string      // Query parameters removed, such as TrackingParams
+	TrailingSlash TrailingSlash // Trailing slash policy of the paths
+}
+
+// Canonicalize returns the canonical form of the link.
+func (c Canonicalizer) Canonicalize(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	if u.Opaque != "" {
+		return link, nil
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port == "" || port == defaultPorts[u.Scheme] {
+		u.Host = strings.TrimSuffix(strings.TrimSuffix(u.Host, port), ":")
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	escaped := normalizeEscapes(u.EscapedPath())
+	if u.Path, err = url.PathUnescape(escaped); err != nil {
+		return "", err
+	}
+	u.RawPath = escaped
+	// Resolving an absolute URL removes the dot segments of its path
+	u = u.ResolveReference(u)
+	if u.Path == "" && u.Host != "" {
+		u.Path, u.RawPath = "/", ""
+	}
+	c.trailingSlash(u)
+
+	u.RawQuery = c.query(u.RawQuery)
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// trailingSlash applies the trailing slash policy to the path of the URL.
+func (c Canonicalizer) trailingSlash(u *url.URL) {
+	switch {
+	case c.TrailingSlash == AddTrailingSlash && !strings.HasSuffix(u.Path, "/"):
+		u.Path += "/"
+		if u.RawPath != "" {
+			u.RawPath += "/"
+		}
+	case c.TrailingSlash == RemoveTrailingSlash && u.Path != "/":
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+}
+
+// query returns the canonical form of a raw query: its percent-encoding is
+// normalized, the empty and stripped parameters are removed, and the
+// parameters are sorted by name if requested.
+func (c Canonicalizer) query(raw string) string {
+	params := []string{}
+	for _, param := range strings.Split(raw, "&") {
+		if param == "" || c.stripped(queryName(param)) {
+			continue
+		}
+		params = append(params, normalizeEscapes(param))
+	}
+	if c.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return queryName(params[i]) < queryName(params[j])
+		})
+	}
+	return strings.Join(params, "&")
+}
+
+// stripped reports whether the query parameter is removed.
+func (c Canonicalizer) stripped(name string) bool {
+	for _, strip := range c.StripParams {
+		if name == strip {
+			return true
+		}
+	}
+	return false
+}
+
+// queryName returns the unescaped name of a raw query parameter.
+func queryName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// normalizeEscapes decodes the percent-encoded unreserved characters of s and
+// uppercases the hexadecimal digits of the remaining escapes.
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				if isUnreserved(byte(c)) {
+					b.WriteByte(byte(c))
+				} else {
+					b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isUnreserved reports whether c is an unreserved character of RFC 3986.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// MergeCanonical merges the results of the pages declaring another crawled URL
+// as their canonical URL into the result of the canonical URL, which lists
+// them in its Duplicates. The URLs are compared in the form given by the
+// canonicalizer. The pages whose canonical URL does not answer 200 OK are kept
+// with ErrCanonicalNotOK, and so are the ones whose canonical URL was not
+// crawled or declares yet another canonical URL.
+func MergeCanonical(results []PageResult, canonicalizer Canonicalizer) []PageResult {
+	index := map[string]int{}
+	for i, result := range results {
+		for _, link := range []string{result.URL, result.FinalURL} {
+			if c, err := canonicalizer.Canonicalize(link); err == nil && link != "" {
+				if _, ok := index[c]; !ok {
+					index[c] = i
+				}
+			}
+		}
+	}
+
+	merged := make([]bool, len(results))
+	for i := range results {
+		result := &results[i]
+		if result.Canonical == "" || result.CanonicalErr != nil {
+			continue
+		}
+		j, ok := index[result.Canonical]
+		if !ok || j == i || results[j].NoFollow {
+			continue
+		}
+		if k, ok := index[results[j].Canonical]; ok && k != j {
+			// Chains of canonical URLs are not merged
+			continue
+		}
+		if target := &results[j]; target.Status != 200 || len(target.Redirects) > 0 {
+			result.CanonicalErr = ErrCanonicalNotOK
+		} else {
+			target.Duplicates = append(target.Duplicates, result.URL)
+			merged[i] = true
+		}
+	}
+
+	list := make([]PageResult, 0, len(results))
+	for i, result := range results {
+		if !merged[i] {
+			list = append(list, result)
+		}
+	}
+	return list
+}
@@ -0,0 +1,202 @@
+package crawler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"http://www.example.com/A", "http://www.example.com/A"},
+		{"HTTP://WWW.Example.COM/A", "http://www.example.com/A"},
+		{"http://www.example.com:80/A", "http://www.example.com/A"},
+		{"https://www.example.com:443/A", "https://www.example.com/A"},
+		{"https://www.example.com:80/A", "https://www.example.com:80/A"},
+		{"http://www.example.com:/A", "http://www.example.com/A"},
+		{"http://www.example.com/A#top", "http://www.example.com/A"},
+		{"http://www.example.com", "http://www.example.com/"},
+		{"http://www.example.com/a/./b/../c", "http://www.example.com/a/c"},
+		{"http://www.example.com/%7Euser/%41", "http://www.example.com/~user/A"},
+		{"http://www.example.com/a%2fb", "http://www.example.com/a%2Fb"},
+		{"http://www.example.com/a%20b", "http://www.example.com/a%20b"},
+		{"http://www.example.com/A?", "http://www.example.com/A"},
+		{"http://www.example.com/A?b=2&&a=1", "http://www.example.com/A?b=2&a=1"},
+		{"http://www.example.com/A?q=%7e%3d", "http://www.example.com/A?q=~%3D"},
+		{"mailto:info@example.com", "mailto:info@example.com"},
+	}
+
+	for _, tt := range tests {
+		canonical, err := crawler.Canonicalizer{}.Canonicalize(tt.link)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expected, canonical, "Canonicalize(%q)", tt.link)
+	}
+
+	_, err := crawler.Canonicalizer{}.Canonicalize(" http://www.example.com")
+	assert.NotNil(t, err)
+}
+
+func TestCanonicalizeQuery(t *testing.T) {
+	canonicalizer := crawler.Canonicalizer{SortQuery: true, StripParams: crawler.TrackingParams}
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"http://www.example.com/A?b=2&a=1", "http://www.example.com/A?a=1&b=2"},
+		{"http://www.example.com/A?b=2&a=1&b=1", "http://www.example.com/A?a=1&b=2&b=1"},
+		{"http://www.example.com/A?utm_source=x", "http://www.example.com/A"},
+		{"http://www.example.com/A?utm_source=x&id=1&fbclid=y", "http://www.example.com/A?id=1"},
+		{"http://www.example.com/A?utm%5Fmedium=x&id=1", "http://www.example.com/A?id=1"},
+	}
+
+	for _, tt := range tests {
+		canonical, err := canonicalizer.Canonicalize(tt.link)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expected, canonical, "Canonicalize(%q)", tt.link)
+	}
+}
+
+func TestCanonicalizeTrailingSlash(t *testing.T) {
+	tests := []struct {
+		policy   crawler.TrailingSlash
+		link     string
+		expected string
+	}{
+		{crawler.KeepTrailingSlash, "http://www.example.com/A/", "http://www.example.com/A/"},
+		{crawler.KeepTrailingSlash, "http://www.example.com/A", "http://www.example.com/A"},
+		{crawler.AddTrailingSlash, "http://www.example.com/A", "http://www.example.com/A/"},
+		{crawler.AddTrailingSlash, "http://www.example.com/A/", "http://www.example.com/A/"},
+		{crawler.AddTrailingSlash, "http://www.example.com/a%2Fb", "http://www.example.com/a%2Fb/"},
+		{crawler.RemoveTrailingSlash, "http://www.example.com/A/", "http://www.example.com/A"},
+		{crawler.RemoveTrailingSlash, "http://www.example.com/", "http://www.example.com/"},
+		{crawler.RemoveTrailingSlash, "http://www.example.com", "http://www.example.com/"},
+	}
+
+	for _, tt := range tests {
+		canonical, err := crawler.Canonicalizer{TrailingSlash: tt.policy}.Canonicalize(tt.link)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expected, canonical, "Canonicalize(%q)", tt.link)
+	}
+}
+
+func TestParseTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected crawler.TrailingSlash
+		fails    bool
+	}{
+		{"keep", crawler.KeepTrailingSlash, false},
+		{"add", crawler.AddTrailingSlash, false},
+		{"remove", crawler.RemoveTrailingSlash, false},
+		{"something", crawler.KeepTrailingSlash, true},
+	}
+	for _, tt := range tests {
+		policy, err := crawler.ParseTrailingSlash(tt.name)
+		assert.Equal(t, tt.expected, policy)
+		assert.Equal(t, tt.fails, err != nil)
+	}
+}
+
+func TestRecursiveCanonicalLinks(t *testing.T) {
+	page := `<html><body>
+		<a href="/A">A</a>
+		<a href="/A#top">A</a>
+		<a href="HTTP://WWW.Example.com:80/A">A</a>
+		<a href="/A?utm_source=x">A</a>
+		<a href="/A/">A</a>
+		<a href="/./B/../A">A</a>
+		<a href="http://www.example.com:80/">Root</a>
+		</body></html>`
+	fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(page))}, nil
+	})
+
+	root, _ := url.Parse("http://www.example.com")
+	opts := crawler.Options{
+		Fetcher: fetcher,
+		Canonicalizer: crawler.Canonicalizer{
+			StripParams:   crawler.TrackingParams,
+			TrailingSlash: crawler.RemoveTrailingSlash,
+		},
+	}
+	result := crawler.NewRecursive(root, opts).Run(context.Background())
+	assert.ElementsMatch(t, []string{"http://www.example.com", "http://www.example.com/A"}, URLs(result))
+}
+
+func TestExtractCanonical(t *testing.T) {
+	tests := []struct {
+		head      string
+		canonical string
+		err       error
+		followed  bool
+	}{
+		{``, "", nil, false},
+		{`<link rel="canonical" href="/A">`, "http://www.example.com/A", nil, true},
+		{`<link rel="Canonical" href="http://www.example.com/">`, "http://www.example.com/", nil, false},
+		{`<link rel="canonical" href="http://www.other.com/A">`, "http://www.other.com/A", crawler.ErrCanonicalOutOfScope, false},
+	}
+
+	for _, tt := range tests {
+		page := extractPage(t, `<html><head>`+tt.head+`</head><body></body></html>`)
+		assert.Equal(t, tt.canonical, page.Result.Canonical, tt.head)
+		assert.Equal(t, tt.err, page.Result.CanonicalErr, tt.head)
+		assert.Equal(t, tt.followed, page.Links[tt.canonical], tt.head)
+	}
+}
+
+func TestMergeCanonical(t *testing.T) {
+	results := []crawler.PageResult{
+		{URL: "http://www.example.com", Status: 200},
+		{URL: "http://www.example.com/A", Status: 200, Canonical: "http://www.example.com/A"},
+		{URL: "http://www.example.com/A?ref=1", Status: 200, Canonical: "http://www.example.com/A"},
+		{URL: "http://www.example.com/a", Status: 200, Canonical: "http://www.example.com/A"},
+		{URL: "http://www.example.com/home", Status: 200, Canonical: "http://www.example.com/"},
+		{URL: "http://www.example.com/B", Status: 200, Canonical: "http://www.example.com/missing"},
+		{URL: "http://www.example.com/C", Status: 200, Canonical: "http://www.example.com/404"},
+		{URL: "http://www.example.com/404", Status: 404},
+		{URL: "http://www.example.com/D", Status: 200, Canonical: "http://www.example.com/moved"},
+		{URL: "http://www.example.com/moved", Status: 200, Redirects: []string{"http://www.example.com/new"}},
+		{URL: "http://www.example.com/E", Status: 200, Canonical: "http://www.other.com/", CanonicalErr: crawler.ErrCanonicalOutOfScope},
+	}
+
+	merged := crawler.MergeCanonical(results, crawler.Canonicalizer{})
+	byURL := map[string]crawler.PageResult{}
+	for _, result := range merged {
+		byURL[result.URL] = result
+	}
+	assert.Equal(t, len(results)-3, len(merged))
+	assert.Equal(t, []string{"http://www.example.com/home"}, byURL["http://www.example.com"].Duplicates)
+	assert.Equal(t, []string{"http://www.example.com/A?ref=1", "http://www.example.com/a"}, byURL["http://www.example.com/A"].Duplicates)
+	assert.Nil(t, byURL["http://www.example.com/A"].CanonicalErr)
+	assert.Nil(t, byURL["http://www.example.com/B"].CanonicalErr)
+	assert.Equal(t, crawler.ErrCanonicalNotOK, byURL["http://www.example.com/C"].CanonicalErr)
+	assert.Equal(t, crawler.ErrCanonicalNotOK, byURL["http://www.example.com/D"].CanonicalErr)
+	assert.Equal(t, crawler.ErrCanonicalOutOfScope, byURL["http://www.example.com/E"].CanonicalErr)
+}
+
+func TestRunMergesCanonical(t *testing.T) {
+	pages := map[string]string{
+		"http://www.example.com":         `<a href="/A?ref=1">A</a><a href="/B">B</a>`,
+		"http://www.example.com/A?ref=1": `<link rel="canonical" href="/A">`,
+		"http://www.example.com/A":       `<link rel="canonical" href="/A">`,
+		"http://www.example.com/B":       `<link rel="canonical" href="/C">`,
+	}
+	fetcher := NewPagesFetcher(pages)
+
+	results, err := crawler.Run(context.Background(), "http://www.example.com", "Recursive", crawler.Options{Fetcher: fetcher})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/B", "http://www.example.com/C"}, URLs(results))
+	assert.Equal(t, []string{"http://www.example.com/A?ref=1"}, results[1].Duplicates)
+	assert.Equal(t, crawler.ErrCanonicalNotOK, results[2].CanonicalErr)
+	assert.Equal(t, 404, results[3].Status)
+}
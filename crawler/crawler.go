@@ -1,49 +1,95 @@
 package crawler
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"net/url"
-	"sort"
 )
 
 // Strategy represents a web crawling strategy.
 type Strategy interface {
-	Run() []string
+	Run(ctx context.Context) []PageResult
+}
+
+// StreamStrategy is a Strategy able to emit the result of every page as soon
+// as it completes, instead of returning them all at the end of the crawl.
+type StreamStrategy interface {
+	Strategy
+	Stream(ctx context.Context, emit func(PageResult))
 }
 
 // Run starts the web crawling process with the specified root URL.
-// It returns the result of the crawl or an error if any occurred.
-func Run(rootUrl string, strategy string, limits Limits) ([]string, error) {
-	// Parse the given URL
-	parsedURL, err := url.Parse(rootUrl)
+// The crawl stops when the context is cancelled, in which case the results of
+// the URLs visited so far are returned.
+// The pages declaring another crawled URL as their canonical URL are merged
+// into its result, see MergeCanonical.
+// The strategy is looked up by name among the registered strategies, see
+// RegisterStrategy.
+// It returns the results of the crawl sorted by URL or an error if any occurred.
+func Run(ctx context.Context, rootUrl string, strategy string, opts Options) ([]PageResult, error) {
+	st, err := newStrategy(rootUrl, strategy, opts)
 	if err != nil {
 		return nil, err
 	}
-	// Create the strategy
-	st, err := createStrategy(parsedURL, strategy, limits)
+	// Run the algorithm
+	result := MergeCanonical(st.Run(ctx), opts.Canonicalizer)
+	SortResults(result)
+	return result, nil
+}
+
+// Stream starts the web crawling process with the specified root URL like Run,
+// but returns a channel of the results sent as the pages complete, in the order
+// of the crawl. The results of the strategies that are not a StreamStrategy are
+// sent once their crawl is complete.
+// The results are neither sorted nor merged under their canonical URL, which
+// requires the whole crawl, so MergeCanonical can be applied to the collected
+// results afterwards.
+// The returned channel will be closed once the crawl is complete or the context
+// is cancelled, in which case the pending results are dropped.
+func Stream(ctx context.Context, rootUrl string, strategy string, opts Options) (<-chan PageResult, error) {
+	st, err := newStrategy(rootUrl, strategy, opts)
 	if err != nil {
 		return nil, err
 	}
-	// Run the algorithm
-	result := st.Run()
-	sort.Strings(result)
-	return result, nil
+	out := make(chan PageResult)
+	go func() {
+		defer close(out)
+		emit := func(result PageResult) {
+			select {
+			case out <- result:
+			case <-ctx.Done():
+			}
+		}
+		if streaming, ok := st.(StreamStrategy); ok {
+			streaming.Stream(ctx, emit)
+			return
+		}
+		for _, result := range st.Run(ctx) {
+			emit(result)
+		}
+	}()
+	return out, nil
+}
+
+// newStrategy parses the root URL and creates the strategy registered under
+// the given name.
+func newStrategy(rootUrl string, strategy string, opts Options) (Strategy, error) {
+	// Parse the given URL
+	parsedURL, err := url.Parse(rootUrl)
+	if err != nil {
+		return nil, err
+	}
+	return createStrategy(parsedURL, strategy, opts)
 }
 
-// createStrategy creates a web crawling strategy based on the provided string.
-func createStrategy(url *url.URL, strategy string, limits Limits) (Strategy, error) {
-	switch strategy {
-	case "OneLevel":
-		return NewOneLevel(url), nil
-	case "Recursive":
-		return NewRecursive(url), nil
-	case "RecursiveParallel":
-		return NewRecursiveParallel(url), nil
-	case "RecursiveWithLimits":
-		return NewRecursiveWithLimits(url, limits), nil
-	case "RecursiveParallelWithLimits":
-		return NewRecursiveParallelWithLimits(url, limits), nil
-	default:
-		return nil, errors.New("error creating strategy")
+// createStrategy creates the web crawling strategy registered under the given
+// name.
+func createStrategy(url *url.URL, name string, opts Options) (Strategy, error) {
+	registryMutex.RLock()
+	strategy, ok := registry[name]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStrategy, name)
 	}
+	return strategy.factory(url, opts), nil
 }
@@ -1,6 +1,7 @@
 package crawler_test
 
 import (
+	"context"
 	"sort"
 	"testing"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var emptyLimits = crawler.Limits{}
+var emptyOptions = crawler.Options{}
 
 func TestRunStrategy(t *testing.T) {
 
@@ -28,7 +29,7 @@ func TestRunStrategy(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_, err := crawler.Run("", tt.strategy, emptyLimits)
+		_, err := crawler.Run(context.Background(), "", tt.strategy, emptyOptions)
 		if tt.fails {
 			assert.NotNil(t, err)
 		}
@@ -60,7 +61,7 @@ func TestRunUrl(t *testing.T) {
 		{"cache_object/:foo/bar", false},
 	}
 	for _, tt := range tests {
-		_, err := crawler.Run(tt.url, "OneLevel", emptyLimits)
+		_, err := crawler.Run(context.Background(), tt.url, "OneLevel", emptyOptions)
 		if tt.fails {
 			assert.NotNil(t, err)
 		} else {
@@ -86,11 +87,35 @@ func TestRunResultIsSorted(t *testing.T) {
 		httpmock.NewStringResponder(200, fileContent))
 
 	// Test the result is sorted
-	resultsA, _ := crawler.Run(strategy, url, emptyLimits)
-	resultsB := make([]string, len(resultsA))
-	copy(resultsB, resultsA)
-	sort.Strings(resultsB)
-	for i, link := range resultsA {
-		assert.Equal(t, link, resultsB[i])
+	results, err := crawler.Run(context.Background(), url, strategy, emptyOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, 25, len(results))
+	assert.True(t, sort.SliceIsSorted(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	}))
+}
+
+func TestStream(t *testing.T) {
+	RegisterStatic("Static")
+	opts := crawler.Options{Fetcher: NewFileFetcher(t, HtmlFiles)}
+	root := "http://www.parserdigital.com"
+
+	for _, strategy := range []string{"OneLevel", "Recursive", "RecursiveParallel", "Static"} {
+		t.Run(strategy, func(t *testing.T) {
+			results, err := crawler.Stream(context.Background(), root, strategy, opts)
+			assert.Nil(t, err)
+			streamed := []crawler.PageResult{}
+			for result := range results {
+				streamed = append(streamed, result)
+			}
+
+			expected, err := crawler.Run(context.Background(), root, strategy, opts)
+			assert.Nil(t, err)
+			assert.NotEmpty(t, streamed)
+			assert.ElementsMatch(t, URLs(expected), URLs(streamed))
+		})
 	}
+
+	_, err := crawler.Stream(context.Background(), root, "Something", opts)
+	assert.ErrorIs(t, err, crawler.ErrUnknownStrategy)
 }
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// directives represents the indexing directives of a page, given by its
+// <meta name="robots"> elements and its X-Robots-Tag headers.
+type directives struct {
+	noIndex  bool // The page asks not to be indexed
+	noFollow bool // The page asks not to follow its links
+}
+
+// pageDirectives returns the directives of a page addressed to every crawler
+// or to the crawlers with the given user agent.
+func pageDirectives(resp *http.Response, doc *html.Node, userAgent string) directives {
+	d := directives{}
+	if resp != nil {
+		for _, value := range resp.Header.Values("X-Robots-Tag") {
+			d.add(headerDirectives(value, userAgent))
+		}
+	}
+	if doc != nil {
+		collectMetaDirectives(doc, userAgent, &d)
+	}
+	return d
+}
+
+// add merges the directives of other into d.
+func (d *directives) add(other string) {
+	for _, token := range strings.Split(other, ",") {
+		switch strings.ToLower(strings.TrimSpace(token)) {
+		case "noindex":
+			d.noIndex = true
+		case "nofollow":
+			d.noFollow = true
+		case "none":
+			d.noIndex, d.noFollow = true, true
+		}
+	}
+}
+
+// headerDirectives returns the directives of an X-Robots-Tag header value, or
+// an empty string if the value is addressed to another user agent, such as
+// "googlebot: noindex".
+func headerDirectives(value, userAgent string) string {
+	agent, rest, ok := strings.Cut(value, ":")
+	if !ok || strings.Contains(agent, ",") {
+		return value
+	}
+	if strings.EqualFold(strings.TrimSpace(agent), userAgent) {
+		return rest
+	}
+	return ""
+}
+
+// collectMetaDirectives recursively adds the directives of the
+// <meta name="robots"> elements of an HTML node, and of the ones named after
+// the user agent, to d.
+func collectMetaDirectives(node *html.Node, userAgent string, d *directives) {
+	if node.Type == html.ElementNode && node.Data == "meta" {
+		name := attrValue(node, "name")
+		if strings.EqualFold(name, "robots") || strings.EqualFold(name, userAgent) {
+			d.add(attrValue(node, "content"))
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		collectMetaDirectives(child, userAgent, d)
+	}
+}
+
+// hasRel reports whether the rel attribute value contains the link type.
+func hasRel(rel, linkType string) bool {
+	for _, field := range strings.Fields(rel) {
+		if strings.EqualFold(field, linkType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package crawler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// extractPage runs the content and the X-Robots-Tag header of a page through
+// the Parse and Extract stages.
+func extractPage(t *testing.T, content string, robotsTag ...string) *crawler.Page {
+	root, _ := url.Parse("http://www.example.com/")
+	header := http.Header{"Content-Type": {"text/html"}}
+	for _, value := range robotsTag {
+		header.Add("X-Robots-Tag", value)
+	}
+	in := make(chan *crawler.Page, 1)
+	in <- &crawler.Page{
+		Result:   crawler.PageResult{URL: root.String(), ContentType: "text/html"},
+		Response: &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader(content))},
+	}
+	close(in)
+	ctx := context.Background()
+	return <-crawler.Extract(ctx, crawler.Parse(ctx, in, 0), crawler.Scope{Root: root}, DefaultExtractor(t))
+}
+
+func TestExtractRelNoFollow(t *testing.T) {
+	page := extractPage(t, `<html><body>
+		<a href="/A">A</a>
+		<a href="/B" rel="nofollow">B</a>
+		<a href="/C" rel="noopener NoFollow">C</a>
+		<a href="/A" rel="nofollow">A</a>
+		</body></html>`)
+
+	assert.Equal(t, map[string]bool{"http://www.example.com/A": true}, page.Links)
+	assert.Equal(t, map[string]bool{"http://www.example.com/B": true, "http://www.example.com/C": true}, page.NoFollow)
+	assert.False(t, page.Result.NoIndex)
+}
+
+func TestExtractMetaRobots(t *testing.T) {
+	tests := []struct {
+		meta     string
+		noIndex  bool
+		noFollow bool
+	}{
+		{`<meta name="robots" content="index, follow">`, false, false},
+		{`<meta name="robots" content="noindex">`, true, false},
+		{`<meta name="robots" content="nofollow">`, false, true},
+		{`<meta name="ROBOTS" content="NoFollow, NoIndex">`, true, true},
+		{`<meta name="robots" content="none">`, true, true},
+		{`<meta name="gocrawler" content="noindex">`, true, false},
+		{`<meta name="googlebot" content="noindex, nofollow">`, false, false},
+	}
+
+	for _, tt := range tests {
+		page := extractPage(t, `<html><head>`+tt.meta+`</head><body><a href="/A">A</a></body></html>`)
+		assert.Equal(t, tt.noIndex, page.Result.NoIndex, tt.meta)
+		assert.Equal(t, tt.noFollow, len(page.NoFollow) == 1, tt.meta)
+		assert.Equal(t, !tt.noFollow, len(page.Links) == 1, tt.meta)
+	}
+}
+
+func TestExtractRobotsTagHeader(t *testing.T) {
+	tests := []struct {
+		header   []string
+		noIndex  bool
+		noFollow bool
+	}{
+		{[]string{"noindex"}, true, false},
+		{[]string{"nofollow"}, false, true},
+		{[]string{"noindex, nofollow"}, true, true},
+		{[]string{"noarchive", "none"}, true, true},
+		{[]string{"gocrawler: nofollow"}, false, true},
+		{[]string{"googlebot: noindex"}, false, false},
+		{[]string{"unavailable_after: 25 Jun 2010 15:00:00 PST"}, false, false},
+	}
+
+	for _, tt := range tests {
+		page := extractPage(t, `<html><body><a href="/A">A</a></body></html>`, tt.header...)
+		assert.Equal(t, tt.noIndex, page.Result.NoIndex, tt.header)
+		assert.Equal(t, tt.noFollow, len(page.NoFollow) == 1, tt.header)
+	}
+}
+
+func TestRecordNoFollow(t *testing.T) {
+	pages := map[string]string{
+		"http://www.example.com":   `<a href="/A">A</a><a href="/B" rel="nofollow">B</a><a href="/C" rel="nofollow">C</a>`,
+		"http://www.example.com/A": `<meta name="robots" content="noindex"><a href="/C">C</a>`,
+		"http://www.example.com/B": ``,
+		"http://www.example.com/C": ``,
+	}
+	fetched := map[string]bool{}
+	pagesFetcher := NewPagesFetcher(pages)
+	fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		fetched[link] = true
+		return pagesFetcher.Fetch(ctx, link)
+	})
+	root, _ := url.Parse("http://www.example.com")
+
+	// The nofollow links are skipped
+	results := crawler.NewRecursive(root, crawler.Options{Fetcher: fetcher}).Run(context.Background())
+	assert.ElementsMatch(t, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/C"}, URLs(results))
+	assert.False(t, fetched["http://www.example.com/B"])
+
+	// The nofollow links are recorded but not fetched
+	fetched = map[string]bool{}
+	opts := crawler.Options{Fetcher: fetcher, RecordNoFollow: true, Workers: 1}
+	for _, strategy := range []crawler.Strategy{
+		crawler.NewRecursive(root, opts),
+		crawler.NewRecursiveParallel(root, opts),
+	} {
+		results := strategy.Run(context.Background())
+		assert.Equal(t, 4, len(results))
+		for _, result := range results {
+			switch result.URL {
+			case "http://www.example.com/B":
+				assert.True(t, result.NoFollow)
+				assert.Equal(t, 0, result.Status)
+				assert.Equal(t, "http://www.example.com", result.Parent)
+			case "http://www.example.com/A":
+				assert.True(t, result.NoIndex)
+			default:
+				// C is followed from A even though the root links it as nofollow
+				assert.False(t, result.NoFollow)
+				assert.Equal(t, 200, result.Status)
+			}
+		}
+		assert.False(t, fetched["http://www.example.com/B"])
+	}
+}
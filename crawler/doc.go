@@ -14,6 +14,11 @@ crawler will stop when it reaches the limits. The limits are not hard limits and
 the crawler may exceed them by a small amount. The crawler will stop as soon as
 it can.
 
+The MaxDepth field of the limits is honored by every recursive strategy. The
+depth of a URL is its shortest distance in links from the root URL: when a URL
+is found again closer to the root URL, whatever the order of the crawl, its
+depth is lowered along with the depths of the URLs found from it.
+
 # Types of strategies
 
 The crawler can be configured to use one of the following strategies:
@@ -36,12 +41,103 @@ This strategy implements a search approach to crawl URLs in parallel,
 discovering new URLs at each level and continuing the crawling process until
 there are no more unvisited URLs.
 
+The URLs are crawled by a pool of workers. The number of workers is set with
+the Workers field of the Options and defaults to DefaultWorkers.
+
 ## Parallel with limits
 
 This strategy implements a search approach to crawl URLs in parallel,
 discovering new URLs at each level and continuing the crawling process until
 there are no more unvisited URLs or the limits are reached.
 
+## Best first
+
+This strategy crawls first the URLs with the highest score given by the Scorer
+of the Options, so that a crawl limited in requests visits the most important
+pages. The built-in scorers favor the URLs closest to the root URL
+(DepthScorer), the URLs linked from the most pages found so far
+(InboundScorer, the default) or the URLs and anchor texts matching keywords
+(NewKeywordScorer). The scores of the pending URLs are updated as new links to
+them are found.
+
+## Best first with limits
+
+This strategy implements the Best first strategy until there are no more
+unvisited URLs or the limits are reached.
+
+## Depth first
+
+This strategy follows each branch of the site to the bottom before
+backtracking, so that deep navigation paths, such as paginated archives, are
+reached under a request budget. A branch is made of a URL found on the root
+page and of every URL found from it, and the BranchPages field of the limits
+caps the pages crawled per branch.
+
+## Depth first with limits
+
+This strategy implements the Depth first strategy until there are no more
+unvisited URLs or the limits are reached.
+
+# Registering strategies
+
+The strategies are run by name. Run looks the name up among the strategies
+registered with RegisterStrategy, so other packages can add their own
+strategies, typically from an init function, and Strategies lists them along
+with their descriptions.
+
+# Engine
+
+The recursive strategies are configurations of the same Engine, which crawls
+the URLs in the order of a Frontier with a number of workers and an optional
+budget of time and requests. The FIFOFrontier crawls breadth first, while a
+PriorityFrontier crawls first the targets ordered first by its function. Other
+orderings are built by passing an EngineConfig to NewEngine.
+
+# Streaming
+
+Stream crawls like Run but returns a channel of the results, sent as the pages
+complete. The strategies implementing StreamStrategy, such as the Engine, emit
+every result as soon as its visit completes, while the results of the other
+strategies are sent at the end of the crawl. The streamed results are neither
+sorted nor merged under their canonical URL.
+
+# Robots
+
+When the Robots field of the Options is set, every strategy honors the
+robots.txt file of the crawled hosts and skips the disallowed URLs before
+downloading them. Each robots.txt file is fetched once and cached. The rules of
+the group matching the crawler's user agent are used, falling back to the "*"
+group, and the Allow and Disallow paths support the "*" and "$" wildcards. The
+Crawl-delay of a host can be honored by passing Robots.CrawlDelay to the
+Politeness settings of a PoliteFetcher.
+
+# Sitemaps
+
+The Sitemaps field of the Options seeds the recursive strategies with the URLs
+listed in the sitemaps of the root URL's host. The sitemaps are found in the
+Sitemap lines of its robots.txt file, or at the conventional /sitemap.xml path,
+and may be sitemap indexes, urlsets or gzipped files of up to
+DefaultMaxSitemapSize bytes. The SitemapAndLinks mode crawls them along with the
+URLs discovered from the links, while the SitemapOnly mode crawls the root URL
+and the sitemap URLs without following any link. The OneLevel strategy collects
+the sitemap URLs without fetching them, along with the links of the root URL in
+the SitemapAndLinks mode.
+
+# Scope
+
+The Scope field of the Options selects the hosts that belong to the crawl. By
+default only the host of the root URL is crawled. The RegistrableDomain mode
+accepts every host sharing the registrable domain of the root URL, as defined
+by the public suffix list, so that www.example.com and blog.example.com are
+crawled together while example.co.uk and other.co.uk are not. The AllowedHosts
+mode accepts the host of the root URL and the hosts listed in Scope.Hosts.
+
+The Scope can further restrict the crawled URLs to a path prefix and to the
+URLs matching its Include patterns, and exclude the URLs matching its Exclude
+patterns. The patterns are globs, or regular expressions when prefixed with
+"re:", and are matched against the canonical form of the URLs before they are
+queued.
+
 # Usage
 
 The following example shows how to use the crawler package to crawl a website
@@ -50,30 +146,25 @@ using the Recursive strategy:
 	package main
 
 	import (
+		"context"
 		"fmt"
 		"log"
-		"net/url"
 
-		"github.com/paconte/gocrawler"
-		"github.com/paconte/crawler/strategies"
+		"github.com/paconte/gocrawler/crawler"
 	)
 
 	func main() {
 
-		// Create a new URL to crawl.
-		url, err := url.Parse("https://www.example.com")
+		// Crawl the website with the strategy registered as Recursive.
+		results, err := crawler.Run(context.Background(), "https://www.example.com", "Recursive", crawler.Options{})
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		// Create a new crawler with the Recursive strategy.
-		c := crawler.New(url, strategies.NewRecursive(url))
-
-		// Start crawling.
-		visited := c.Crawl()
-
 		// Print the visited URLs.
-		fmt.Println(visited)
+		for _, result := range results {
+			fmt.Println(result.URL)
+		}
 	}
 
 The following example shows how to use the crawler package to crawl a website
@@ -82,13 +173,12 @@ using the Recursive with limits strategy:
 	package main
 
 	import (
+		"context"
 		"fmt"
 		"log"
 		"net/url"
-		"time"
 
-		"github.com/paconte/gocrawler"
-		"github.com/paconte/crawler/strategies"
+		"github.com/paconte/gocrawler/crawler"
 	)
 
 	func main() {
@@ -99,39 +189,118 @@ using the Recursive with limits strategy:
 			log.Fatal(err)
 		}
 
-		// Create a new crawler with the Recursive with limits strategy.
-		c := crawler.New(url, strategies.NewRecursiveWithLimits(url, crawler.Limits{
-			Milliseconds: 1000,
-			Requests:     100,
-		}))
+		// Create the Recursive with limits strategy.
+		strategy := crawler.NewRecursiveWithLimits(url, crawler.Options{
+			Limits: crawler.Limits{
+				Milliseconds: 1000,
+				Requests:     100,
+			},
+		})
 
 		// Start crawling.
-		visited := c.Crawl()
+		results := strategy.Run(context.Background())
 
 		// Print the visited URLs.
-		fmt.Println(visited)
+		for _, result := range results {
+			fmt.Println(result.URL)
+		}
 	}
 
 # Pipeline
 
-The crawler package uses a pipeline to crawl the web. The pipeline is composed
-of the following stages:
+The crawler package uses a pipeline to crawl the web. A Page flows through the
+stages, each of them filling in its fields and recording any failure in the
+PageResult of the page instead of dropping it. The strategies return the
+PageResult of every crawled URL, with its status, content type, size, timing,
+error, depth and the page it was found on. The pipeline is composed of the
+following stages:
 
 ## Download
 
-# The Download stage downloads the content of a URL and returns a string
+# The Download stage downloads the content of a URL and returns a Page with the response
+
+The content is retrieved with a Fetcher. HTTPFetcher is the default
+implementation and wraps an http.Client, but any type implementing the Fetcher
+interface, such as a FetcherFunc serving pages from memory, can be passed to
+the strategies.
+
+PoliteFetcher wraps another Fetcher and throttles the requests per host: it
+limits the requests per second, enforces a minimum delay between two requests
+and caps the concurrent requests to the same host.
+
+HTTPFetcher reports the redirect loops and the redirect chains longer than
+DefaultMaxRedirects as errors. The redirect hops of every URL are recorded in
+its PageResult, even when the chain fails. The strategies stop following a
+redirect chain before downloading the first hop outside the crawled domain,
+reported with ErrRedirectOutOfScope, or disallowed by the robots rules,
+reported with ErrRedirectDisallowed.
+
+CacheTransport is an http.RoundTripper that can be used by the client of an
+HTTPFetcher to keep an on-disk cache of the responses. The cached responses are
+revalidated with the If-None-Match and If-Modified-Since headers on later
+crawls, and the cached body is reused when the server answers 304 Not Modified.
+
+RetryFetcher wraps another Fetcher and retries transient network errors, such
+as timeouts and reset connections, and the responses with a retryable status
+code, such as 429 or 503, following a RetryPolicy. It waits with an exponential
+backoff with jitter between the attempts, honors the Retry-After header and
+reports the number of attempts made for every URL, wherever it is in the chain
+of fetchers.
 
 ## Parse
 
-# The Parse stage parses the content of a URL and returns a slice of URLs
+# The Parse stage parses the content of a Page and sets its HTML document
+
+Only the HTML and XHTML responses are parsed, at most Options.MaxBodySize bytes
+are read from each body, and every response body is closed. Setting the Probe
+field of an HTTPFetcher sends a HEAD request first, so that binary content is
+not downloaded at all.
 
 ## Extract
 
-# The Extract stage extracts the URLs that match the root URL and returns a slice
+# The Extract stage extracts the URLs of a Page that match the root URL
+
+The links are resolved against the URL of the page they are found on, or
+against its <base href> element, so the relative links are extracted as
+absolute URLs.
+
+The links are found by a LinkExtractor. HTMLLinkExtractor is the default
+implementation and extracts the links of the <a>, <area>, <link>, <iframe>,
+<frame>, <form action>, <img src/srcset>, <script src>, <source> and
+<meta http-equiv=refresh> elements, each of them tagged with the element and
+attribute it was found on. Only the DefaultLinkKinds, which lead to other
+pages, are crawled unless other kinds are given to NewHTMLLinkExtractor.
+
+Every link found on a page, in or out of the scope, is recorded in the Links of
+its PageResult as an edge of the link graph, with the page it was found on, its
+anchor text, element, attribute, rel and title.
+
+The links marked rel="nofollow" are not followed, and neither are the links of
+the pages asking not to follow them with a <meta name="robots"> element or an
+X-Robots-Tag header. Setting the RecordNoFollow field of the Options reports
+their targets, flagged with NoFollow, without fetching them. The pages asking
+not to be indexed are flagged with NoIndex in their PageResult.
+
+The URL of the <link rel="canonical"> element of every page is recorded in its
+PageResult and crawled when it belongs to the scope. Run merges the results of
+the pages declaring another crawled URL as their canonical URL into the result
+of that URL with MergeCanonical, and reports the canonical URLs outside the
+scope with ErrCanonicalOutOfScope and the ones not answering 200 OK with
+ErrCanonicalNotOK.
+
+## Normalize
+
+# The Normalize stage rewrites the extracted URLs in their canonical form
+
+The scheme and host are lowercased, the default port and the fragment are
+dropped, and the percent-encoding and the dot segments of the path are
+normalized, so that the different spellings of a URL are crawled once. The
+Canonicalizer field of the Options can also sort the query parameters, strip
+the tracking parameters and add or remove the trailing slash of the paths.
 
 ## Collect
 
-# The Collect stage collects the URLs and returns a map
+# The Collect stage collects the Pages and returns a slice
 
 ## MapToList
 
@@ -9,34 +9,70 @@ import (
 // IsSubdomain checks if a given link is a subdomain of the specified domain.
 // It returns true if the link is a subdomain, false otherwise.
 // If the domain is exactly the same as the link, it returns false.
+// Only the links with the same host as the domain are accepted, see Scope for
+// other host modes.
 func IsSubdomain(link string, domain *url.URL) bool {
-	u, err := url.Parse(link)
-	if err != nil {
-		return false
-	}
-	result := u.Hostname() == domain.Hostname() &&
-		u.RequestURI() != domain.RequestURI() // Avoid same url
-	return result
+	return Scope{Root: domain}.IsSubdomain(link)
 }
 
-// GetSubdomains recursively extracts subdomains from an HTML node.
-// It returns a map of subdomains found in the HTML node.
-func GetSubdomains(node *html.Node, domain *url.URL) map[string]bool {
+// GetSubdomains extracts the subdomains of the scope from an HTML document
+// with the given link extractor.
+// The links are resolved against the URL of the page the document was
+// downloaded from, or against the first <base href> element of the document
+// if there is one, before checking whether they are subdomains.
+// It returns a map of the absolute URLs of the subdomains found in the document.
+func GetSubdomains(node *html.Node, page *url.URL, scope Scope, extractor LinkExtractor) map[string]bool {
 	var links = make(map[string]bool)
-	if node.Type == html.ElementNode && node.Data == "a" {
+	for _, link := range extractor.ExtractLinks(node, baseURL(node, page)) {
+		if scope.IsSubdomain(link.URL) {
+			links[link.URL] = true
+		}
+	}
+	return links
+}
+
+// baseURL returns the URL the relative links of an HTML document resolve
+// against: the href of its first <base> element resolved against the page
+// URL, or the page URL itself.
+func baseURL(node *html.Node, page *url.URL) *url.URL {
+	if href, ok := findBaseHref(node); ok {
+		if u, err := url.Parse(href); err == nil {
+			return page.ResolveReference(u)
+		}
+	}
+	return page
+}
+
+// findBaseHref recursively looks for the href of the first <base> element of
+// an HTML node.
+func findBaseHref(node *html.Node) (string, bool) {
+	if node.Type == html.ElementNode && node.Data == "base" {
 		for _, attr := range node.Attr {
-			if attr.Key == "href" && IsSubdomain(attr.Val, domain) {
-				links[attr.Val] = true
+			if attr.Key == "href" {
+				return attr.Val, true
 			}
 		}
 	}
-
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		child_links := GetSubdomains(child, domain)
-		for k := range child_links {
-			links[k] = true
+		if href, ok := findBaseHref(child); ok {
+			return href, true
 		}
 	}
+	return "", false
+}
 
-	return links
+// findCanonicalHref recursively looks for the href of the first
+// <link rel="canonical"> element of an HTML node.
+func findCanonicalHref(node *html.Node) (string, bool) {
+	if node.Type == html.ElementNode && node.Data == "link" && hasRel(attrValue(node, "rel"), "canonical") {
+		if href := attrValue(node, "href"); href != "" {
+			return href, true
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if href, ok := findCanonicalHref(child); ok {
+			return href, true
+		}
+	}
+	return "", false
 }
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	crawler "github.com/paconte/gocrawler/crawler"
@@ -38,6 +39,7 @@ var TargetLinks []string = []string{
 	"https://parserdigital.com/category/qa/",
 	"https://parserdigital.com/category/women-in-tech/",
 	"https://parserdigital.com/my-experience-at-stareast/",
+	"https://parserdigital.com/career",
 }
 
 func OpenFile(relativePath string) ([]byte, error) {
@@ -108,7 +110,7 @@ func TestGetSubdomains(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	result := crawler.GetSubdomains(doc, domain)
+	result := crawler.GetSubdomains(doc, domain, crawler.Scope{Root: domain}, DefaultExtractor(t))
 
 	// Check the result
 	expected := TargetLinks
@@ -118,3 +120,33 @@ func TestGetSubdomains(t *testing.T) {
 		assert.True(t, ok, "GetSubdomains() missing link %q", k)
 	}
 }
+
+func TestGetSubdomainsRelative(t *testing.T) {
+	domain, _ := url.Parse("https://parserdigital.com/")
+	tests := []struct {
+		page     string
+		content  string
+		expected []string
+	}{
+		{"https://parserdigital.com/blog/", `<a href="/about">About</a><a href="post">Post</a><a href="../">Up</a>`, []string{
+			"https://parserdigital.com/about",
+			"https://parserdigital.com/blog/post",
+		}},
+		{"https://parserdigital.com/blog/", `<a href="//parserdigital.com/x">X</a><a href="mailto:a@b.c">Mail</a>`, []string{
+			"https://parserdigital.com/x",
+		}},
+		{"https://parserdigital.com/blog/", `<head><base href="/docs/"></head><a href="page">Page</a>`, []string{
+			"https://parserdigital.com/docs/page",
+		}},
+		{"https://parserdigital.com/blog/", `<head><base href="https://example.com/"></head><a href="page">Page</a>`, []string{}},
+		{"https://parserdigital.com/", `<a href="#top">Top</a><a href="/">Root</a>`, []string{}},
+	}
+
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.content))
+		assert.Nil(t, err)
+		page, _ := url.Parse(tt.page)
+		result := crawler.GetSubdomains(doc, page, crawler.Scope{Root: domain}, DefaultExtractor(t))
+		assert.ElementsMatch(t, tt.expected, crawler.MapToList(result))
+	}
+}
@@ -0,0 +1,215 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// DefaultWorkers is the number of workers used by the parallel strategies when
+// no concurrency level is configured.
+const DefaultWorkers = 10
+
+// EngineConfig represents the configuration of an Engine: the ordering of the
+// crawl, its concurrency and whether it has a budget.
+type EngineConfig struct {
+	Frontier func() Frontier // Creates the frontier ordering the crawl, NewFIFOFrontier by default
+	Workers  int             // Number of concurrent visits, Options.Workers if not positive
+	Budget   bool            // Whether the Milliseconds and Requests of the Options.Limits apply
+}
+
+// Engine is the crawl engine behind the recursive strategies. It crawls the
+// root URL and every URL discovered from it, in the order of its frontier,
+// with a fixed number of workers. A single coordinator goroutine owns the
+// frontier and the found set, so the visits do not need any locking and every
+// URL is queued once.
+type Engine struct {
+	url    *url.URL // Root URL
+	opts   Options
+	config EngineConfig
+}
+
+// NewEngine creates a new Engine crawling the root URL.
+func NewEngine(url *url.URL, opts Options, config EngineConfig) *Engine {
+	opts = opts.withDefaults()
+	if config.Frontier == nil {
+		config.Frontier = NewFIFOFrontier
+	}
+	if config.Workers <= 0 {
+		config.Workers = opts.Workers
+	}
+	return &Engine{url: url, opts: opts, config: config}
+}
+
+// Run starts the web crawling process. The time limit of a budget is applied
+// on top of the given context.
+// It returns the results of the visited URLs, or of the URLs visited so far if
+// the context is cancelled, along with the nofollow targets that were not
+// followed. URLs whose visit was interrupted by the context are not reported.
+// Every result has the shortest depth found for its URL during the crawl.
+func (e *Engine) Run(ctx context.Context) []PageResult {
+	results := []PageResult{}
+	found := e.stream(ctx, func(result PageResult) {
+		results = append(results, result)
+	})
+	for i, result := range results {
+		if target, ok := found[result.URL]; ok && !result.NoFollow {
+			results[i].Depth, results[i].Parent = target.Depth, target.Parent
+		}
+	}
+	return results
+}
+
+// Stream starts the web crawling process like Run, but emits the result of
+// every visited URL as soon as its visit completes, then the nofollow targets
+// that were not followed. The results are emitted from a single goroutine and
+// the crawl waits for emit to return.
+// A result is emitted with the shortest depth found for its URL so far, a
+// shorter path found afterwards only lowers the depths of the URLs found from
+// it.
+func (e *Engine) Stream(ctx context.Context, emit func(PageResult)) {
+	e.stream(ctx, emit)
+}
+
+// stream runs the crawl of Stream and returns the targets found, with their
+// shortest depth, by URL.
+func (e *Engine) stream(ctx context.Context, emit func(PageResult)) map[string]PageResult {
+	requests := -1 // No limit
+	if e.config.Budget {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(e.opts.Limits.Milliseconds)*time.Millisecond)
+		defer cancel()
+		requests = e.opts.Limits.Requests
+	}
+
+	if !e.opts.allowed(ctx, e.url.String()) {
+		return nil
+	}
+	seeds := append([]PageResult{rootTarget(e.url)}, e.opts.sitemapTargets(ctx, e.url)...)
+	return e.crawl(ctx, seeds, requests, emit)
+}
+
+// visitResult is the outcome of the visit of a target.
+type visitResult struct {
+	result   PageResult
+	children []PageResult
+	ok       bool
+}
+
+// crawl visits the seeds and every target discovered from them until the
+// frontier is empty, the request limit is reached or the context is cancelled,
+// and emits their results. A negative request limit means no limit.
+// When a target is found again closer to the root URL, its depth and parent
+// are lowered: a pending target is pushed again and the stale entry skipped,
+// while the targets found on a visited target are pushed again one level
+// below it, so that the maximum depth applies to the shortest paths whatever
+// the order of the frontier.
+// It returns the targets found, with their shortest depth, by URL.
+func (e *Engine) crawl(ctx context.Context, seeds []PageResult, requests int, emit func(PageResult)) map[string]PageResult {
+	queue := make(chan PageResult)
+	results := make(chan visitResult)
+	for i := 0; i < e.config.Workers; i++ {
+		go func() {
+			for target := range queue {
+				result, children, ok := e.opts.visit(ctx, target, e.url)
+				results <- visitResult{result: result, children: children, ok: ok}
+			}
+		}()
+	}
+	defer close(queue)
+
+	found := map[string]PageResult{}   // Found targets with their shortest depth
+	started := map[string]bool{}       // Targets sent to a worker
+	links := map[string][]PageResult{} // Targets found on the visited targets
+	noFollow := map[string]PageResult{}
+	followed := func(link string) bool {
+		_, ok := found[link]
+		return ok
+	}
+	frontier := e.config.Frontier()
+	var push func(targets []PageResult)
+	push = func(targets []PageResult) {
+		for _, target := range followTargets(e.opts.withinDepth(targets), noFollow) {
+			known, ok := found[target.URL]
+			if ok && target.Depth >= known.Depth {
+				continue
+			}
+			found[target.URL] = target
+			if children, visited := links[target.URL]; visited {
+				push(childTargets(children, target))
+			} else if !started[target.URL] {
+				frontier.Push(target)
+			}
+		}
+	}
+	push(seeds)
+
+	var next *PageResult // Target popped from the frontier, waiting for a worker
+	inflight, dispatched := 0, 0
+	stopped := false
+	done := ctx.Done()
+
+	for {
+		// Only pop a target once a worker is free, so that the targets found
+		// meanwhile can take precedence, while there is budget left and the
+		// crawl is alive
+		if next == nil && inflight < e.config.Workers && !stopped && (requests < 0 || dispatched < requests) {
+			for target, ok := frontier.Pop(); ok; target, ok = frontier.Pop() {
+				// Skip the entries of the targets pushed again closer to the root
+				if !started[target.URL] && target.Depth == found[target.URL].Depth {
+					next = &target
+					break
+				}
+			}
+		}
+		var send chan PageResult
+		var target PageResult
+		if next != nil && !stopped {
+			send, target = queue, *next
+		}
+		if send == nil && inflight == 0 {
+			for _, result := range noFollowResults(noFollow, followed) {
+				emit(result)
+			}
+			return found
+		}
+
+		select {
+		case send <- target:
+			started[target.URL] = true
+			next = nil
+			inflight++
+			dispatched++
+		case res := <-results:
+			inflight--
+			if !res.ok || ctx.Err() != nil {
+				continue
+			}
+			// The target may have been found closer to the root during its visit
+			target := found[res.result.URL]
+			res.result.Depth, res.result.Parent = target.Depth, target.Parent
+			emit(res.result)
+			if linked, ok := frontier.(LinkFrontier); ok {
+				for _, link := range res.result.Links {
+					linked.Link(link)
+				}
+			}
+			links[target.URL] = res.children
+			push(childTargets(res.children, target))
+		case <-done:
+			// Stop dispatching and wait for the in-flight visits
+			stopped = true
+			done = nil
+		}
+	}
+}
+
+// childTargets returns the targets found on the parent, one level below it.
+func childTargets(children []PageResult, parent PageResult) []PageResult {
+	targets := make([]PageResult, 0, len(children))
+	for _, child := range children {
+		child.Depth, child.Parent = parent.Depth+1, parent.URL
+		targets = append(targets, child)
+	}
+	return targets
+}
@@ -0,0 +1,102 @@
+package crawler_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineFrontier(t *testing.T) {
+	files := NewFileFetcher(t, HtmlFiles)
+
+	// Record the order of the fetches, the level 3 pages link to C and D
+	var mutex sync.Mutex
+	fetched := []string{}
+	fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		mutex.Lock()
+		fetched = append(fetched, link)
+		mutex.Unlock()
+		return files.Fetch(ctx, link)
+	})
+
+	descending := func(a, b crawler.PageResult) bool { return a.URL > b.URL }
+	config := crawler.EngineConfig{
+		Frontier: func() crawler.Frontier { return crawler.NewPriorityFrontier(descending) },
+		Workers:  1,
+	}
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	engine := crawler.NewEngine(parsedUrl, crawler.Options{Fetcher: fetcher}, config)
+
+	results := engine.Run(context.Background())
+	assert.Equal(t, 7, len(results))
+	assert.Equal(t, []string{
+		"http://www.parserdigital.com",
+		"http://www.parserdigital.com/B",
+		"http://www.parserdigital.com/F",
+		"http://www.parserdigital.com/E",
+		"http://www.parserdigital.com/D",
+		"http://www.parserdigital.com/C",
+		"http://www.parserdigital.com/A",
+	}, fetched)
+}
+
+func TestEngineBudget(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	tests := []struct {
+		name     string
+		config   crawler.EngineConfig
+		expected int
+	}{
+		{"no budget", crawler.EngineConfig{}, 7},
+		{"budget", crawler.EngineConfig{Budget: true}, 3},
+		{"budget sequential", crawler.EngineConfig{Workers: 1, Budget: true}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := crawler.Options{
+				Fetcher: NewFileFetcher(t, HtmlFiles),
+				Limits:  crawler.Limits{Milliseconds: 100 * 1000, Requests: 3},
+			}
+			results := crawler.NewEngine(parsedUrl, opts, tt.config).Run(context.Background())
+			assert.Equal(t, tt.expected, len(results))
+		})
+	}
+}
+
+func TestEngineStream(t *testing.T) {
+	files := NewFileFetcher(t, HtmlFiles)
+
+	// Count the results emitted before every fetch
+	var mutex sync.Mutex
+	emitted := 0
+	emittedBefore := map[string]int{}
+	fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		mutex.Lock()
+		emittedBefore[link] = emitted
+		mutex.Unlock()
+		return files.Fetch(ctx, link)
+	})
+
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	engine := crawler.NewRecursive(parsedUrl, crawler.Options{Fetcher: fetcher})
+	engine.Stream(context.Background(), func(result crawler.PageResult) {
+		mutex.Lock()
+		emitted++
+		mutex.Unlock()
+	})
+
+	assert.Equal(t, 7, emitted)
+	assert.Equal(t, 0, emittedBefore["http://www.parserdigital.com"])
+	for link, count := range emittedBefore {
+		if link != "http://www.parserdigital.com" {
+			assert.Greater(t, count, 0, link)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxRedirects is the maximum length of a redirect chain followed by an
+// HTTPFetcher.
+const DefaultMaxRedirects = 10
+
+var (
+	// ErrRedirectLoop is returned when a redirect chain visits a URL twice.
+	ErrRedirectLoop = errors.New("redirect loop")
+	// ErrTooManyRedirects is returned when a redirect chain is too long.
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Fetcher retrieves the content of a URL.
+// Implementations can wrap custom http.Client instances, record responses or
+// serve them from memory. Implementations should abort the request when the
+// context is cancelled.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*http.Response, error)
+}
+
+// FetcherFunc is an adapter to allow the use of ordinary functions as Fetchers.
+type FetcherFunc func(ctx context.Context, url string) (*http.Response, error)
+
+// Fetch calls f(ctx, url).
+func (f FetcherFunc) Fetch(ctx context.Context, url string) (*http.Response, error) {
+	return f(ctx, url)
+}
+
+// HTTPFetcher is the default Fetcher. It performs GET requests with an http.Client.
+type HTTPFetcher struct {
+	client *http.Client
+
+	// Probe enables HEAD-first probing. When set, a HEAD request is sent
+	// before every GET request and the URLs announcing a non HTML content type
+	// are not downloaded: the response of the HEAD request is returned instead.
+	Probe bool
+}
+
+// NewHTTPFetcher creates a new HTTPFetcher using the given client.
+// If client is nil, http.DefaultClient is used. Unless the client defines its
+// own redirect policy, redirect loops and chains longer than
+// DefaultMaxRedirects are reported as errors.
+func NewHTTPFetcher(client *http.Client) *HTTPFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if client.CheckRedirect == nil {
+		copy := *client
+		copy.CheckRedirect = checkRedirect
+		client = &copy
+	}
+	return &HTTPFetcher{client: client}
+}
+
+// checkRedirect stops the redirect chains that loop or are too long, and the
+// hops rejected by the redirect check of the request context.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= DefaultMaxRedirects {
+		return ErrTooManyRedirects
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return ErrRedirectLoop
+		}
+	}
+	if check, ok := req.Context().Value(redirectCheckKey{}).(func(*url.URL) error); ok {
+		return check(req.URL)
+	}
+	return nil
+}
+
+// redirectCheckKey is the context key of the check of the redirect hops.
+type redirectCheckKey struct{}
+
+// withRedirectCheck returns a copy of the context in which an HTTPFetcher
+// stops following the redirects at the first hop for which check returns an
+// error, before downloading it, and returns the error.
+func withRedirectCheck(ctx context.Context, check func(hop *url.URL) error) context.Context {
+	return context.WithValue(ctx, redirectCheckKey{}, check)
+}
+
+// noProbeKey is the context key of the fetches that must not be probed.
+type noProbeKey struct{}
+
+// withoutProbe returns a copy of the context in which an HTTPFetcher always
+// downloads the content, even with Probe set, for the fetches of content that
+// is not HTML such as the robots.txt files and the sitemaps. The context goes
+// through the fetchers wrapping the HTTPFetcher.
+func withoutProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noProbeKey{}, true)
+}
+
+// Fetch performs a GET request to the specified URL bound to the given context.
+// The request identifies itself with the DefaultUserAgent.
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*http.Response, error) {
+	if f.Probe && ctx.Value(noProbeKey{}) == nil {
+		resp, err := f.do(ctx, http.MethodHead, url)
+		if err == nil {
+			contentType := resp.Header.Get("Content-Type")
+			if resp.StatusCode < 400 && contentType != "" && !IsHTML(contentType) {
+				return resp, nil
+			}
+			resp.Body.Close()
+		}
+	}
+	return f.do(ctx, http.MethodGet, url)
+}
+
+// do performs a request with the given method to the specified URL.
+func (f *HTTPFetcher) do(ctx context.Context, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	return f.client.Do(req)
+}
@@ -0,0 +1,124 @@
+package crawler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// NewContentFetcher returns an in-memory Fetcher serving the given contents by
+// URL as they are. Unknown URLs produce a 404 Not Found.
+func NewContentFetcher(contents map[string]string) crawler.Fetcher {
+	return crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		content, ok := contents[link]
+		if !ok {
+			return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(content))}, nil
+	})
+}
+
+// NewFileFetcher returns an in-memory Fetcher serving the given files by URL.
+// Unknown URLs produce a 404 Not Found.
+func NewFileFetcher(t *testing.T, files map[string]string) crawler.Fetcher {
+	contents := make(map[string]string, len(files))
+	for link, file := range files {
+		contents[link] = LoadFileAsString(t, file)
+	}
+	return NewContentFetcher(contents)
+}
+
+// NewPagesFetcher returns an in-memory Fetcher serving the given HTML bodies by
+// URL, wrapped in an <html> element. Unknown URLs produce a 404 Not Found.
+func NewPagesFetcher(pages map[string]string) crawler.Fetcher {
+	contents := make(map[string]string, len(pages))
+	for link, page := range pages {
+		contents[link] = "<html>" + page + "</html>"
+	}
+	return NewContentFetcher(contents)
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	url := "https://parserdigital.com/"
+
+	// Use a custom client with its own mocked transport
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", url, httpmock.NewStringResponder(200, "content"))
+	fetcher := crawler.NewHTTPFetcher(&http.Client{Transport: transport})
+
+	resp, err := fetcher.Fetch(context.Background(), url)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 1, transport.GetTotalCallCount())
+
+	_, err = fetcher.Fetch(context.Background(), "https://google.com")
+	assert.NotNil(t, err)
+}
+
+func TestFetcherFunc(t *testing.T) {
+	fetcher := NewFileFetcher(t, HtmlFiles)
+
+	// Test the strategy runs without touching the network
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	strategy := crawler.NewRecursive(parsedUrl, crawler.Options{Fetcher: fetcher})
+	result := strategy.Run(context.Background())
+	assert.Equal(t, 7, len(result))
+}
+
+func TestHTTPFetcherCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := crawler.NewHTTPFetcher(server.Client()).Fetch(ctx, server.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestHTTPFetcherProbe(t *testing.T) {
+	gets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file.pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		if r.Method == http.MethodGet {
+			gets++
+			w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		probe  bool
+		method string
+		gets   int
+	}{
+		{"/file.pdf", false, http.MethodGet, 1},
+		{"/file.pdf", true, http.MethodHead, 0},
+		{"/page", true, http.MethodGet, 1},
+	}
+	for _, tt := range tests {
+		gets = 0
+		fetcher := crawler.NewHTTPFetcher(server.Client())
+		fetcher.Probe = tt.probe
+		resp, err := fetcher.Fetch(context.Background(), server.URL+tt.path)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.method, resp.Request.Method)
+		assert.Equal(t, tt.gets, gets)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,355 @@
+package crawler
+
+import (
+	"container/heap"
+)
+
+// Frontier is the queue of the targets waiting to be crawled. Its ordering
+// decides which target is crawled next.
+type Frontier interface {
+	// Push adds a target to the frontier.
+	Push(target PageResult)
+	// Pop removes and returns the next target to crawl, or false if the
+	// frontier is empty.
+	Pop() (PageResult, bool)
+	// Len returns the number of targets in the frontier.
+	Len() int
+}
+
+// FIFOFrontier is a first in, first out Frontier: the targets are crawled in
+// the order they were found, so the crawl is breadth first.
+type FIFOFrontier struct {
+	targets []PageResult
+}
+
+// NewFIFOFrontier creates a new, empty FIFOFrontier.
+func NewFIFOFrontier() Frontier {
+	return &FIFOFrontier{}
+}
+
+// Push adds a target to the back of the queue.
+func (f *FIFOFrontier) Push(target PageResult) {
+	f.targets = append(f.targets, target)
+}
+
+// Pop removes and returns the target at the front of the queue.
+func (f *FIFOFrontier) Pop() (PageResult, bool) {
+	if len(f.targets) == 0 {
+		return PageResult{}, false
+	}
+	target := f.targets[0]
+	f.targets[0] = PageResult{} // Release the target for the garbage collector
+	f.targets = f.targets[1:]
+	return target, true
+}
+
+// Len returns the number of targets in the queue.
+func (f *FIFOFrontier) Len() int {
+	return len(f.targets)
+}
+
+// LIFOFrontier is a last in, first out Frontier: the last target found is
+// crawled first, so the crawl is depth first.
+type LIFOFrontier struct {
+	targets []PageResult
+}
+
+// NewLIFOFrontier creates a new, empty LIFOFrontier.
+func NewLIFOFrontier() Frontier {
+	return &LIFOFrontier{}
+}
+
+// Push adds a target to the top of the stack.
+func (f *LIFOFrontier) Push(target PageResult) {
+	f.targets = append(f.targets, target)
+}
+
+// Pop removes and returns the target at the top of the stack.
+func (f *LIFOFrontier) Pop() (PageResult, bool) {
+	if len(f.targets) == 0 {
+		return PageResult{}, false
+	}
+	last := len(f.targets) - 1
+	target := f.targets[last]
+	f.targets[last] = PageResult{} // Release the target for the garbage collector
+	f.targets = f.targets[:last]
+	return target, true
+}
+
+// Len returns the number of targets in the stack.
+func (f *LIFOFrontier) Len() int {
+	return len(f.targets)
+}
+
+// BranchFrontier is a Frontier crawling at most a number of targets of every
+// branch of the site, in the order of another frontier. A branch is made of a
+// target found on the root page, or listed in a sitemap, and of every target
+// found from it. The root URL does not belong to any branch.
+// The targets of a branch that reached the maximum are dropped. A target pushed
+// again moves to the branch of its new parent, and its previous entries are
+// dropped without counting.
+type BranchFrontier struct {
+	frontier Frontier
+	max      int
+	branches map[string]string // Branches of the pushed targets by URL
+	depths   map[string]int    // Depths of the last push of the targets by URL
+	crawled  map[string]int    // Number of popped targets by branch
+}
+
+// NewBranchFrontier creates a new BranchFrontier popping at most max targets
+// of every branch from the given frontier.
+func NewBranchFrontier(frontier Frontier, max int) Frontier {
+	return &BranchFrontier{
+		frontier: frontier,
+		max:      max,
+		branches: map[string]string{},
+		depths:   map[string]int{},
+		crawled:  map[string]int{},
+	}
+}
+
+// Push adds a target to the frontier, in the branch of its parent.
+func (f *BranchFrontier) Push(target PageResult) {
+	if target.Depth > 0 {
+		branch, ok := f.branches[target.Parent]
+		if !ok || target.Depth == 1 {
+			branch = target.URL
+		}
+		f.branches[target.URL] = branch
+	}
+	f.depths[target.URL] = target.Depth
+	f.frontier.Push(target)
+}
+
+// Pop removes and returns the next target whose branch did not reach the
+// maximum, dropping the others.
+func (f *BranchFrontier) Pop() (PageResult, bool) {
+	for {
+		target, ok := f.frontier.Pop()
+		if !ok {
+			return PageResult{}, false
+		}
+		if target.Depth != f.depths[target.URL] {
+			continue
+		}
+		branch, ok := f.branches[target.URL]
+		if !ok {
+			return target, true
+		}
+		if f.crawled[branch] < f.max {
+			f.crawled[branch]++
+			return target, true
+		}
+	}
+}
+
+// Len returns the number of targets in the frontier, including the ones that
+// will be dropped.
+func (f *BranchFrontier) Len() int {
+	return f.frontier.Len()
+}
+
+// PriorityFrontier is a Frontier crawling first the target that orders before
+// the others. The targets ordered the same are crawled in the order they were
+// found.
+type PriorityFrontier struct {
+	queue priorityQueue
+}
+
+// NewPriorityFrontier creates a new, empty PriorityFrontier ordering the
+// targets with less, which reports whether a is crawled before b.
+func NewPriorityFrontier(less func(a, b PageResult) bool) Frontier {
+	return &PriorityFrontier{queue: priorityQueue{less: less}}
+}
+
+// Push adds a target to the frontier.
+func (f *PriorityFrontier) Push(target PageResult) {
+	heap.Push(&f.queue, priorityItem{target: target, order: f.queue.pushed})
+	f.queue.pushed++
+}
+
+// Pop removes and returns the target that orders first.
+func (f *PriorityFrontier) Pop() (PageResult, bool) {
+	if f.queue.Len() == 0 {
+		return PageResult{}, false
+	}
+	return heap.Pop(&f.queue).(priorityItem).target, true
+}
+
+// Len returns the number of targets in the frontier.
+func (f *PriorityFrontier) Len() int {
+	return f.queue.Len()
+}
+
+// priorityItem is a target of a priorityQueue along with its insertion order.
+type priorityItem struct {
+	target PageResult
+	order  int
+}
+
+// priorityQueue implements heap.Interface for the PriorityFrontier.
+type priorityQueue struct {
+	items  []priorityItem
+	less   func(a, b PageResult) bool
+	pushed int // Number of items ever pushed, breaking the ties
+}
+
+func (q priorityQueue) Len() int { return len(q.items) }
+
+func (q priorityQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if q.less(a.target, b.target) {
+		return true
+	}
+	if q.less(b.target, a.target) {
+		return false
+	}
+	return a.order < b.order
+}
+
+func (q priorityQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *priorityQueue) Push(x any) { q.items = append(q.items, x.(priorityItem)) }
+
+func (q *priorityQueue) Pop() any {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items = q.items[:last]
+	return item
+}
+
+// LinkFrontier is a Frontier told about the links found on the crawled pages,
+// including the links to the targets already pushed, so that it can order the
+// targets by the links pointing to them.
+type LinkFrontier interface {
+	Frontier
+	// Link records a link found on a crawled page.
+	Link(link Link)
+}
+
+// ScoredFrontier is a LinkFrontier crawling first the target with the highest
+// score. The score of a pending target is updated whenever a new link to it is
+// found. The targets with the same score are crawled in the order they were
+// found.
+type ScoredFrontier struct {
+	scorer  Scorer
+	links   map[string]*inboundLinks // Inbound links by target URL
+	pending map[string]*scoredItem   // Pending targets by URL
+	queue   scoredQueue
+	pushed  int // Number of targets ever pushed, breaking the ties
+}
+
+// inboundLinks represents the links found to a target.
+type inboundLinks struct {
+	sources map[string]bool // Pages linking to the target
+	anchors []string
+}
+
+// NewScoredFrontier creates a new, empty ScoredFrontier ordering the targets
+// with the given scorer.
+func NewScoredFrontier(scorer Scorer) Frontier {
+	return &ScoredFrontier{
+		scorer:  scorer,
+		links:   map[string]*inboundLinks{},
+		pending: map[string]*scoredItem{},
+	}
+}
+
+// Push adds a target to the frontier.
+func (f *ScoredFrontier) Push(target PageResult) {
+	item := &scoredItem{target: target, order: f.pushed}
+	f.pushed++
+	item.score = f.score(target)
+	f.pending[target.URL] = item
+	heap.Push(&f.queue, item)
+}
+
+// Pop removes and returns the target with the highest score.
+func (f *ScoredFrontier) Pop() (PageResult, bool) {
+	if f.queue.Len() == 0 {
+		return PageResult{}, false
+	}
+	item := heap.Pop(&f.queue).(*scoredItem)
+	if f.pending[item.target.URL] == item {
+		delete(f.pending, item.target.URL)
+	}
+	return item.target, true
+}
+
+// Len returns the number of targets in the frontier.
+func (f *ScoredFrontier) Len() int {
+	return f.queue.Len()
+}
+
+// Link records a link found on a crawled page and rescores its target if it is
+// pending. A target counts one inbound link per page linking to it, whatever
+// the number of links of the page. The links marked rel="nofollow" and the
+// links of a page to itself are ignored.
+func (f *ScoredFrontier) Link(link Link) {
+	if link.URL == link.Source || hasRel(link.Rel, "nofollow") {
+		return
+	}
+	inbound, ok := f.links[link.URL]
+	if !ok {
+		inbound = &inboundLinks{sources: map[string]bool{}}
+		f.links[link.URL] = inbound
+	}
+	inbound.sources[link.Source] = true
+	if link.Text != "" {
+		inbound.anchors = append(inbound.anchors, link.Text)
+	}
+	if item, ok := f.pending[link.URL]; ok {
+		item.score = f.score(item.target)
+		heap.Fix(&f.queue, item.index)
+	}
+}
+
+// score returns the score of the target according to the links found to it.
+func (f *ScoredFrontier) score(target PageResult) float64 {
+	candidate := Candidate{Target: target}
+	if inbound, ok := f.links[target.URL]; ok {
+		candidate.Inbound, candidate.Anchors = len(inbound.sources), inbound.anchors
+	}
+	return f.scorer.Score(candidate)
+}
+
+// scoredItem is a target of a scoredQueue along with its score, insertion
+// order and index in the heap.
+type scoredItem struct {
+	target PageResult
+	score  float64
+	order  int
+	index  int
+}
+
+// scoredQueue implements heap.Interface for the ScoredFrontier.
+type scoredQueue []*scoredItem
+
+func (q scoredQueue) Len() int { return len(q) }
+
+func (q scoredQueue) Less(i, j int) bool {
+	if q[i].score != q[j].score {
+		return q[i].score > q[j].score
+	}
+	return q[i].order < q[j].order
+}
+
+func (q scoredQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *scoredQueue) Push(x any) {
+	item := x.(*scoredItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *scoredQueue) Pop() any {
+	old := *q
+	last := len(old) - 1
+	item := old[last]
+	old[last] = nil // Release the item for the garbage collector
+	*q = old[:last]
+	return item
+}
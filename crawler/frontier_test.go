@@ -0,0 +1,94 @@
+package crawler_test
+
+import (
+	"testing"
+
+	"github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// PopAll pops the URLs of all the targets of the frontier.
+func PopAll(frontier crawler.Frontier) []string {
+	urls := []string{}
+	for {
+		target, ok := frontier.Pop()
+		if !ok {
+			return urls
+		}
+		urls = append(urls, target.URL)
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	byDepth := func(a, b crawler.PageResult) bool { return a.Depth < b.Depth }
+	tests := []struct {
+		name     string
+		frontier crawler.Frontier
+		expected []string
+	}{
+		{"fifo", crawler.NewFIFOFrontier(), []string{"a", "b", "c", "d"}},
+		{"lifo", crawler.NewLIFOFrontier(), []string{"d", "c", "b", "a"}},
+		{"priority", crawler.NewPriorityFrontier(byDepth), []string{"b", "d", "a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frontier.Push(crawler.PageResult{URL: "a", Depth: 2})
+			tt.frontier.Push(crawler.PageResult{URL: "b", Depth: 1})
+			tt.frontier.Push(crawler.PageResult{URL: "c", Depth: 2})
+			tt.frontier.Push(crawler.PageResult{URL: "d", Depth: 1})
+			assert.Equal(t, 4, tt.frontier.Len())
+			assert.Equal(t, tt.expected, PopAll(tt.frontier))
+			assert.Equal(t, 0, tt.frontier.Len())
+
+			_, ok := tt.frontier.Pop()
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestScoredFrontier(t *testing.T) {
+	frontier := crawler.NewScoredFrontier(crawler.InboundScorer)
+	linked := frontier.(crawler.LinkFrontier)
+
+	linked.Link(crawler.Link{Source: "root", URL: "c"})
+	frontier.Push(crawler.PageResult{URL: "a"})
+	frontier.Push(crawler.PageResult{URL: "b"})
+	frontier.Push(crawler.PageResult{URL: "c"})
+	frontier.Push(crawler.PageResult{URL: "d"})
+
+	// Pending targets are rescored, the nofollow and self links are ignored and
+	// a page links once to a target however many links it has, the ties being
+	// popped in the order of the pushes
+	linked.Link(crawler.Link{Source: "x", URL: "b"})
+	linked.Link(crawler.Link{Source: "y", URL: "b"})
+	linked.Link(crawler.Link{Source: "z", URL: "a"})
+	linked.Link(crawler.Link{Source: "z", URL: "a"})
+	linked.Link(crawler.Link{Source: "z", URL: "a"})
+	linked.Link(crawler.Link{Source: "x", URL: "d", Rel: "nofollow"})
+	linked.Link(crawler.Link{Source: "d", URL: "d"})
+	linked.Link(crawler.Link{Source: "x", URL: "e"})
+
+	assert.Equal(t, 4, frontier.Len())
+	assert.Equal(t, []string{"b", "a", "c", "d"}, PopAll(frontier))
+}
+
+func TestBranchFrontier(t *testing.T) {
+	frontier := crawler.NewBranchFrontier(crawler.NewFIFOFrontier(), 2)
+	frontier.Push(crawler.PageResult{URL: "root"})
+	frontier.Push(crawler.PageResult{URL: "a", Depth: 1, Parent: "root"})
+	frontier.Push(crawler.PageResult{URL: "b", Depth: 1, Parent: "root"})
+	frontier.Push(crawler.PageResult{URL: "a1", Depth: 2, Parent: "a"})
+	frontier.Push(crawler.PageResult{URL: "a2", Depth: 3, Parent: "a1"})
+	frontier.Push(crawler.PageResult{URL: "b1", Depth: 2, Parent: "b"})
+	frontier.Push(crawler.PageResult{URL: "s", Depth: 1, Parent: "http://www.example.com/sitemap.xml"})
+	frontier.Push(crawler.PageResult{URL: "c", Depth: 3, Parent: "a1"})
+	frontier.Push(crawler.PageResult{URL: "c", Depth: 2, Parent: "s"})
+
+	// a2 is dropped once a and a1 are popped from the branch of a, and c moved
+	// to the branch of s
+	assert.Equal(t, 9, frontier.Len())
+	assert.Equal(t, []string{"root", "a", "b", "a1", "b1", "s", "c"}, PopAll(frontier))
+	assert.Equal(t, 0, frontier.Len())
+}
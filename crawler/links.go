@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link represents a link found in an HTML document: an edge of the link graph
+// from the page it was found on to its URL.
+type Link struct {
+	Source string // URL of the page the link was found on, set by Extract
+	URL    string // Absolute URL of the link
+	Text   string // Anchor text of the link, or alternative text of an image
+	Tag    string // Element the link was found on, such as "a" or "img"
+	Attr   string // Attribute the link was found on, such as "href" or "srcset"
+	Rel    string // Rel attribute of the element, such as "nofollow"
+	Title  string // Title attribute of the element
+}
+
+// LinkExtractor is the interface implemented by the types extracting the links
+// of an HTML document.
+type LinkExtractor interface {
+	// ExtractLinks returns the links of the document resolved against base.
+	ExtractLinks(doc *html.Node, base *url.URL) []Link
+}
+
+// LinkExtractorFunc is an adapter to allow the use of ordinary functions as
+// link extractors.
+type LinkExtractorFunc func(doc *html.Node, base *url.URL) []Link
+
+// ExtractLinks calls f(doc, base).
+func (f LinkExtractorFunc) ExtractLinks(doc *html.Node, base *url.URL) []Link {
+	return f(doc, base)
+}
+
+// linkAttrs maps the kinds of links, named after their element, to the
+// attributes holding their URLs.
+var linkAttrs = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"link":   {"href"},
+	"iframe": {"src"},
+	"frame":  {"src"},
+	"form":   {"action"},
+	"img":    {"src", "srcset"},
+	"script": {"src"},
+	"source": {"src", "srcset"},
+	"meta":   {"content"},
+}
+
+// DefaultLinkKinds are the kinds of links crawled by default: the ones leading
+// to other pages rather than to resources such as images or scripts.
+var DefaultLinkKinds = []string{"a", "area", "frame", "iframe", "meta"}
+
+// HTMLLinkExtractor is the default LinkExtractor. It extracts the links of the
+// <a>, <area>, <link>, <iframe>, <frame>, <form action>, <img src/srcset>,
+// <script src>, <source> and <meta http-equiv=refresh> elements.
+type HTMLLinkExtractor struct {
+	kinds map[string]bool
+}
+
+// NewHTMLLinkExtractor creates a new HTMLLinkExtractor extracting the given
+// kinds of links, or the DefaultLinkKinds if none is given.
+// It returns an error if a kind is not supported.
+func NewHTMLLinkExtractor(kinds ...string) (*HTMLLinkExtractor, error) {
+	if len(kinds) == 0 {
+		kinds = DefaultLinkKinds
+	}
+	extractor := &HTMLLinkExtractor{kinds: map[string]bool{}}
+	for _, kind := range kinds {
+		if _, ok := linkAttrs[kind]; !ok {
+			return nil, fmt.Errorf("unknown link kind %q", kind)
+		}
+		extractor.kinds[kind] = true
+	}
+	return extractor, nil
+}
+
+// ExtractLinks returns the links of the document resolved against base.
+func (e *HTMLLinkExtractor) ExtractLinks(doc *html.Node, base *url.URL) []Link {
+	links := []Link{}
+	e.collect(doc, base, &links)
+	return links
+}
+
+// collect recursively appends the links found in an HTML node to links.
+func (e *HTMLLinkExtractor) collect(node *html.Node, base *url.URL, links *[]Link) {
+	if node.Type == html.ElementNode && e.kinds[node.Data] {
+		for _, attr := range node.Attr {
+			for _, ref := range linkRefs(node, attr) {
+				href, err := url.Parse(strings.TrimSpace(ref))
+				if err != nil {
+					continue
+				}
+				*links = append(*links, Link{
+					URL:   base.ResolveReference(href).String(),
+					Text:  linkText(node),
+					Tag:   node.Data,
+					Attr:  attr.Key,
+					Rel:   attrValue(node, "rel"),
+					Title: attrValue(node, "title"),
+				})
+			}
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		e.collect(child, base, links)
+	}
+}
+
+// linkRefs returns the URL references held by an attribute of a link element.
+func linkRefs(node *html.Node, attr html.Attribute) []string {
+	if !hasLinkAttr(node.Data, attr.Key) {
+		return nil
+	}
+	switch {
+	case attr.Key == "srcset":
+		return srcsetRefs(attr.Val)
+	case node.Data == "meta":
+		if !strings.EqualFold(attrValue(node, "http-equiv"), "refresh") {
+			return nil
+		}
+		if ref, ok := refreshRef(attr.Val); ok {
+			return []string{ref}
+		}
+		return nil
+	}
+	return []string{attr.Val}
+}
+
+// hasLinkAttr reports whether the attribute of the element holds a URL.
+func hasLinkAttr(tag, key string) bool {
+	for _, attr := range linkAttrs[tag] {
+		if attr == key {
+			return true
+		}
+	}
+	return false
+}
+
+// linkText returns the text of a link element with its whitespace collapsed:
+// the text of its descendants, including the alternative text of their
+// images, or its own alternative text for the <area> and <img> elements.
+func linkText(node *html.Node) string {
+	if node.Data == "area" || node.Data == "img" {
+		return strings.Join(strings.Fields(attrValue(node, "alt")), " ")
+	}
+	var b strings.Builder
+	collectText(node, &b)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// collectText recursively writes the text of the descendants of an HTML node.
+func collectText(node *html.Node, b *strings.Builder) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode:
+			b.WriteString(child.Data + " ")
+		case child.Type == html.ElementNode && child.Data == "img":
+			b.WriteString(attrValue(child, "alt") + " ")
+		default:
+			collectText(child, b)
+		}
+	}
+}
+
+// attrValue returns the value of the attribute of an HTML node with the given key.
+func attrValue(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// srcsetRefs returns the URLs of the image candidates of a srcset attribute,
+// such as "small.jpg 480w, large.jpg 1080w".
+func srcsetRefs(srcset string) []string {
+	refs := []string{}
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs
+}
+
+// refreshRef returns the URL of the content of a <meta http-equiv=refresh>
+// element, such as "5; url=/next".
+func refreshRef(content string) (string, bool) {
+	_, ref, ok := strings.Cut(content, ";")
+	if !ok {
+		_, ref, ok = strings.Cut(content, ",")
+	}
+	ref = strings.TrimSpace(ref)
+	if !ok || len(ref) < 4 || !strings.EqualFold(ref[:3], "url") {
+		return "", false
+	}
+	ref = strings.TrimSpace(ref[3:])
+	if !strings.HasPrefix(ref, "=") {
+		return "", false
+	}
+	ref = strings.Trim(strings.TrimSpace(ref[1:]), `"'`)
+	return ref, ref != ""
+}
@@ -0,0 +1,167 @@
+package crawler_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+// DefaultExtractor returns an HTMLLinkExtractor of the default link kinds.
+func DefaultExtractor(t *testing.T) crawler.LinkExtractor {
+	extractor, err := crawler.NewHTMLLinkExtractor()
+	if err != nil {
+		t.Fatalf("Error creating the link extractor: %v", err)
+	}
+	return extractor
+}
+
+var linksPage = `<html><head>
+	<link rel="next" href="/page2">
+	<meta http-equiv="refresh" content="5; URL='/refresh'">
+	<meta name="description" content="url=/not-a-link">
+	<script src="/app.js"></script>
+	</head><body>
+	<a href=" /A ">A</a>
+	<map><area href="/area"></map>
+	<iframe src="/iframe"></iframe>
+	<form action="/search"></form>
+	<img src="/img.png" srcset="/small.png 480w, /large.png 2x">
+	<picture><source srcset="/pic.webp" type="image/webp"></picture>
+	<video><source src="/video.mp4"></video>
+	</body></html>`
+
+func TestHTMLLinkExtractor(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(linksPage))
+	base, _ := url.Parse("http://www.example.com/dir/")
+
+	extractor, err := crawler.NewHTMLLinkExtractor(
+		"a", "area", "link", "iframe", "frame", "form", "img", "script", "source", "meta")
+	assert.Nil(t, err)
+
+	expected := []crawler.Link{
+		{URL: "http://www.example.com/page2", Tag: "link", Attr: "href", Rel: "next"},
+		{URL: "http://www.example.com/refresh", Tag: "meta", Attr: "content"},
+		{URL: "http://www.example.com/app.js", Tag: "script", Attr: "src"},
+		{URL: "http://www.example.com/A", Text: "A", Tag: "a", Attr: "href"},
+		{URL: "http://www.example.com/area", Tag: "area", Attr: "href"},
+		{URL: "http://www.example.com/iframe", Tag: "iframe", Attr: "src"},
+		{URL: "http://www.example.com/search", Tag: "form", Attr: "action"},
+		{URL: "http://www.example.com/img.png", Tag: "img", Attr: "src"},
+		{URL: "http://www.example.com/small.png", Tag: "img", Attr: "srcset"},
+		{URL: "http://www.example.com/large.png", Tag: "img", Attr: "srcset"},
+		{URL: "http://www.example.com/pic.webp", Tag: "source", Attr: "srcset"},
+		{URL: "http://www.example.com/video.mp4", Tag: "source", Attr: "src"},
+	}
+	assert.ElementsMatch(t, expected, extractor.ExtractLinks(doc, base))
+}
+
+func TestHTMLLinkExtractorKinds(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(linksPage))
+	base, _ := url.Parse("http://www.example.com/")
+
+	links := DefaultExtractor(t).ExtractLinks(doc, base)
+	tags := map[string]bool{}
+	for _, link := range links {
+		tags[link.Tag] = true
+	}
+	assert.Equal(t, map[string]bool{"a": true, "area": true, "iframe": true, "meta": true}, tags)
+
+	frames, _ := html.Parse(strings.NewReader(`<html><frameset><frame src="/frame"></frameset></html>`))
+	expected := []crawler.Link{{URL: "http://www.example.com/frame", Tag: "frame", Attr: "src"}}
+	assert.Equal(t, expected, DefaultExtractor(t).ExtractLinks(frames, base))
+
+	extractor, err := crawler.NewHTMLLinkExtractor("img")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(extractor.ExtractLinks(doc, base)))
+
+	_, err = crawler.NewHTMLLinkExtractor("a", "video")
+	assert.NotNil(t, err)
+}
+
+func TestMetaRefresh(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"0; url=/next", []string{"http://www.example.com/next"}},
+		{"0;URL = \"/next\"", []string{"http://www.example.com/next"}},
+		{"0, url=/next", []string{"http://www.example.com/next"}},
+		{"5", []string{}},
+		{"0; /next", []string{}},
+	}
+
+	extractor, _ := crawler.NewHTMLLinkExtractor("meta")
+	base, _ := url.Parse("http://www.example.com/")
+	for _, tt := range tests {
+		doc, _ := html.Parse(strings.NewReader(`<meta http-equiv="Refresh" content='` + tt.content + `'>`))
+		urls := []string{}
+		for _, link := range extractor.ExtractLinks(doc, base) {
+			urls = append(urls, link.URL)
+		}
+		assert.Equal(t, tt.expected, urls, "content %q", tt.content)
+	}
+}
+
+func TestLinkExtractorFunc(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(linksPage))
+	root, _ := url.Parse("http://www.example.com/")
+	extractor := crawler.LinkExtractorFunc(func(doc *html.Node, base *url.URL) []crawler.Link {
+		return []crawler.Link{{URL: "http://www.example.com/custom"}, {URL: "http://www.other.com/"}}
+	})
+
+	links := crawler.GetSubdomains(doc, root, crawler.Scope{Root: root}, extractor)
+	assert.Equal(t, map[string]bool{"http://www.example.com/custom": true}, links)
+}
+
+func TestLinkText(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<html><body>
+		<a href="/A" title="Go to A">  Page
+			<b>A</b> </a>
+		<a href="/B"><img src="/b.png" alt="Logo B"> and text</a>
+		<map><area href="/C" alt="Area C"></map>
+		</body></html>`))
+	base, _ := url.Parse("http://www.example.com/")
+	extractor, _ := crawler.NewHTMLLinkExtractor("a", "area", "img")
+
+	expected := []crawler.Link{
+		{URL: "http://www.example.com/A", Text: "Page A", Tag: "a", Attr: "href", Title: "Go to A"},
+		{URL: "http://www.example.com/B", Text: "Logo B and text", Tag: "a", Attr: "href"},
+		{URL: "http://www.example.com/b.png", Text: "Logo B", Tag: "img", Attr: "src"},
+		{URL: "http://www.example.com/C", Text: "Area C", Tag: "area", Attr: "href"},
+	}
+	assert.Equal(t, expected, extractor.ExtractLinks(doc, base))
+}
+
+func TestRecursiveLinkEdges(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	opts := crawler.Options{Fetcher: NewFileFetcher(t, HtmlFiles)}
+	results := crawler.NewRecursive(parsedUrl, opts).Run(context.Background())
+
+	for _, result := range results {
+		assert.NotEmpty(t, result.Links)
+		for _, link := range result.Links {
+			assert.Equal(t, result.URL, link.Source)
+			assert.Equal(t, "a", link.Tag)
+			assert.NotEmpty(t, link.Text)
+		}
+	}
+
+	// The edges of the root page
+	for _, result := range results {
+		if result.URL != "http://www.parserdigital.com" {
+			continue
+		}
+		targets := map[string]string{}
+		for _, link := range result.Links {
+			targets[link.URL] = link.Text
+		}
+		assert.Equal(t, "Website A", targets["http://www.parserdigital.com/A"])
+		assert.Equal(t, "Website Root", targets["http://www.parserdigital.com/"])
+	}
+}
@@ -1,68 +1,314 @@
 package crawler
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"golang.org/x/net/html"
 )
 
-// Download asynchronously downloads the specified URLs and returns a channel of *http.Response.
-// Each response will be sent on the channel as it becomes available.
-// The returned channel will be closed once all downloads are complete.
-func Download(url ...string) <-chan *http.Response {
-	out := make(chan *http.Response)
+// DefaultMaxBodySize is the maximum number of bytes read from a response body
+// when no limit is configured.
+const DefaultMaxBodySize = 10 << 20
+
+var (
+	// ErrBodyTooLarge is recorded in the result of a page whose body exceeds
+	// the maximum body size. The page is parsed up to the limit.
+	ErrBodyTooLarge = errors.New("response body too large")
+	// ErrRedirectOutOfScope is recorded in the result of a page redirected
+	// outside the crawled domain. No links are extracted from it.
+	ErrRedirectOutOfScope = errors.New("redirected out of scope")
+	// ErrRedirectDisallowed is recorded in the result of a page redirected to
+	// a URL disallowed by the robots rules, which is not downloaded.
+	ErrRedirectDisallowed = errors.New("redirected to a disallowed URL")
+	// ErrCanonicalOutOfScope is recorded as the CanonicalErr of a page whose
+	// canonical URL is outside the scope.
+	ErrCanonicalOutOfScope = errors.New("canonical out of scope")
+	// ErrCanonicalNotOK is recorded as the CanonicalErr of a page whose
+	// canonical URL does not answer 200 OK without redirects.
+	ErrCanonicalNotOK = errors.New("canonical not 200 OK")
+)
+
+// IsHTML reports whether the content type is HTML or XHTML.
+func IsHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// Download asynchronously downloads the specified URLs with the given fetcher and
+// returns a channel of *Page.
+// A page is sent on the channel for every URL as soon as it is downloaded, with
+// the fetch error recorded in its result if the download failed.
+// The returned channel will be closed once all downloads are complete or the
+// context is cancelled.
+func Download(ctx context.Context, fetcher Fetcher, url ...string) <-chan *Page {
+	out := make(chan *Page)
 	go func() {
+		defer close(out)
 		for _, u := range url {
-			resp, err := http.Get(u)
-			if err == nil {
-				out <- resp
+			if ctx.Err() != nil {
+				return
+			}
+			page := &Page{Result: PageResult{URL: u, Attempts: 1}, start: time.Now()}
+			resp, err := fetcher.Fetch(withAttempts(ctx, &page.Result.Attempts), u)
+			if err != nil {
+				page.Result.Err = err
+				if resp != nil {
+					// The redirect chain stopped by the client, whose body is closed
+					page.Result.Redirects = stoppedRedirectChain(resp)
+				}
+			} else {
+				page.Response = resp
+				page.Result.Status = resp.StatusCode
+				page.Result.ContentType = resp.Header.Get("Content-Type")
+				page.Result.FinalURL = u
+				if resp.Request != nil && resp.Request.URL != nil {
+					page.Result.FinalURL = resp.Request.URL.String()
+				}
+				page.Result.Redirects = redirectChain(resp)
+			}
+			select {
+			case out <- page:
+			case <-ctx.Done():
+				if resp != nil {
+					resp.Body.Close()
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Parse asynchronously parses the HTML documents of the pages received on the input channel.
+// It returns a channel of *Page with the parsed document, or the parse error
+// recorded in the result.
+// Only the HTML and XHTML responses are parsed, sniffing the content when the
+// response has no Content-Type, and at most maxBodySize bytes are read from
+// each body, or the whole body if maxBodySize is not positive. Every response
+// body is closed.
+// The returned channel will be closed once all parsing is complete or the
+// context is cancelled.
+func Parse(ctx context.Context, pages <-chan *Page, maxBodySize int64) <-chan *Page {
+	out := make(chan *Page)
+	go func() {
+		defer close(out)
+		for page := range pages {
+			if page.Response != nil {
+				if ctx.Err() == nil {
+					parseBody(page, maxBodySize)
+				}
+				page.Response.Body.Close()
+			}
+			page.Result.Duration = time.Since(page.start)
+			select {
+			case out <- page:
+			case <-ctx.Done():
 			}
 		}
-		close(out)
 	}()
 	return out
 }
 
-// Parse asynchronously parses the HTML nodes in the *http.Response objects received on the input channel.
-// It returns a channel of *html.Node containing the parsed nodes.
-// The returned channel will be closed once all parsing is complete.
-func Parse(nodes <-chan *http.Response) <-chan *html.Node {
-	out := make(chan *html.Node)
+// Extract asynchronously extracts the subdomains of the scope from the pages received on the input channel
+// with the given link extractor.
+// It returns a channel of *Page with the extracted links, resolved against the
+// URL of their page.
+// Pages redirected outside the scope are reported with ErrRedirectOutOfScope
+// and no links, unless the redirect chain was already stopped with an error.
+// The links marked rel="nofollow", and all the links of the pages asking not to
+// follow them with a <meta name="robots"> element or an X-Robots-Tag header,
+// are set in the NoFollow links of the page instead. The pages asking not to be
+// indexed are flagged with NoIndex.
+// Every link found on a page, in or out of the scope, is recorded as an edge in
+// the Links of its result.
+// The URL of the <link rel="canonical"> element of a page is set in its result
+// and, if it belongs to the scope, in its links so that it is crawled.
+// The returned channel will be closed once all extraction is complete or the
+// context is cancelled.
+func Extract(ctx context.Context, pages <-chan *Page, scope Scope, extractor LinkExtractor) <-chan *Page {
+	out := make(chan *Page)
 	go func() {
-		for resp := range nodes {
-			doc, err := html.Parse(resp.Body)
-			if err == nil {
-				out <- doc
+		defer close(out)
+		for page := range pages {
+			page.Links, page.NoFollow = map[string]bool{}, map[string]bool{}
+			if page.Result.Err == nil && !redirectInScope(page.Result, scope) {
+				page.Result.Err = ErrRedirectOutOfScope
+			} else {
+				extractLinks(page, scope, extractor)
+			}
+			select {
+			case out <- page:
+			case <-ctx.Done():
 			}
 		}
-		close(out)
 	}()
 	return out
 }
 
-// Parse asynchronously parses the HTML nodes in the *http.Response objects received on the input channel.
-// It returns a channel of *html.Node containing the parsed nodes.
-// The returned channel will be closed once all parsing is complete.
-func Extract(nodes <-chan *html.Node, url *url.URL) <-chan string {
-	out := make(chan string)
+// Normalize asynchronously rewrites the links, the nofollow links and the link
+// edges of the pages received on the input channel in their canonical form, so
+// that the spellings of the same URL are deduplicated. The links whose
+// canonical form is the root URL, or that cannot be parsed, are dropped.
+// The returned channel will be closed once all normalization is complete or the
+// context is cancelled.
+func Normalize(ctx context.Context, pages <-chan *Page, canonicalizer Canonicalizer, root *url.URL) <-chan *Page {
+	out := make(chan *Page)
 	go func() {
-		for node := range nodes {
-			for link := range GetSubdomains(node, url) {
-				out <- link
+		defer close(out)
+		rootLink, _ := canonicalizer.Canonicalize(root.String())
+		for page := range pages {
+			page.Links = canonicalLinks(page.Links, canonicalizer, rootLink)
+			page.NoFollow = canonicalLinks(page.NoFollow, canonicalizer, rootLink)
+			if c, err := canonicalizer.Canonicalize(page.Result.Canonical); err == nil && page.Result.Canonical != "" {
+				page.Result.Canonical = c
+			}
+			for i, link := range page.Result.Links {
+				if c, err := canonicalizer.Canonicalize(link.URL); err == nil {
+					page.Result.Links[i].URL = c
+				}
+			}
+			for link := range page.Links {
+				delete(page.NoFollow, link)
+			}
+			select {
+			case out <- page:
+			case <-ctx.Done():
 			}
 		}
-		close(out)
 	}()
 	return out
 }
 
-// CollectMap reads strings from the input channel and collects them into a map.
-// It returns a map containing the collected strings as keys, with a value of true.
-func CollectMap(links <-chan string) map[string]bool {
-	result := make(map[string]bool)
+// canonicalLinks returns the canonical form of the links, except the root link.
+func canonicalLinks(links map[string]bool, canonicalizer Canonicalizer, rootLink string) map[string]bool {
+	canonical := make(map[string]bool, len(links))
 	for link := range links {
-		result[link] = true
+		if c, err := canonicalizer.Canonicalize(link); err == nil && c != rootLink {
+			canonical[c] = true
+		}
+	}
+	return canonical
+}
+
+// extractLinks sets the links of the page and flags it according to its
+// indexing directives.
+func extractLinks(page *Page, scope Scope, extractor LinkExtractor) {
+	d := pageDirectives(page.Response, page.Doc, DefaultUserAgent)
+	page.Result.NoIndex = d.noIndex
+	if page.Doc == nil {
+		return
+	}
+	page.Result.Canonical, page.Result.CanonicalErr = canonicalLink(page, scope)
+
+	page.Result.Links = extractor.ExtractLinks(page.Doc, baseURL(page.Doc, pageURL(page.Result, scope.Root)))
+	links := []Link{}
+	for i := range page.Result.Links {
+		page.Result.Links[i].Source = page.Result.URL
+		if scope.IsSubdomain(page.Result.Links[i].URL) {
+			links = append(links, page.Result.Links[i])
+		}
+	}
+	if page.Result.Canonical != "" && page.Result.CanonicalErr == nil && scope.IsSubdomain(page.Result.Canonical) {
+		// The canonical URL is crawled to check it and merge its duplicates
+		links = append(links, Link{URL: page.Result.Canonical, Tag: "link", Attr: "href", Rel: "canonical"})
+	}
+	for _, link := range links {
+		if d.noFollow || hasRel(link.Rel, "nofollow") {
+			page.NoFollow[link.URL] = true
+		} else {
+			page.Links[link.URL] = true
+		}
+	}
+	for link := range page.Links {
+		delete(page.NoFollow, link)
+	}
+}
+
+// canonicalLink returns the absolute URL of the <link rel="canonical"> element
+// of the page, if any, with ErrCanonicalOutOfScope if it is outside the scope.
+func canonicalLink(page *Page, scope Scope) (string, error) {
+	href, ok := findCanonicalHref(page.Doc)
+	if !ok {
+		return "", nil
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", nil
+	}
+	canonical := baseURL(page.Doc, pageURL(page.Result, scope.Root)).ResolveReference(ref)
+	if !scope.Contains(canonical) {
+		return canonical.String(), ErrCanonicalOutOfScope
+	}
+	return canonical.String(), nil
+}
+
+// parseBody parses the response body of the page if it is HTML.
+func parseBody(page *Page, maxBodySize int64) {
+	body := &countingReader{reader: page.Response.Body}
+	var reader io.Reader = body
+	if maxBodySize > 0 {
+		reader = io.LimitReader(body, maxBodySize)
+	}
+	buffered := bufio.NewReader(reader)
+	defer func() { page.Result.Size = body.count }()
+
+	contentType := page.Result.ContentType
+	if contentType == "" {
+		head, _ := buffered.Peek(512)
+		contentType = http.DetectContentType(head)
+	}
+	if !IsHTML(contentType) {
+		return
+	}
+
+	doc, err := html.Parse(buffered)
+	if err != nil {
+		page.Result.Err = err
+		return
+	}
+	page.Doc = doc
+	if maxBodySize > 0 && body.count >= maxBodySize {
+		if n, _ := page.Response.Body.Read(make([]byte, 1)); n > 0 {
+			page.Result.Err = ErrBodyTooLarge
+		}
+	}
+}
+
+// pageURL returns the URL the page was downloaded from, falling back to the
+// domain if it is unknown.
+func pageURL(result PageResult, domain *url.URL) *url.URL {
+	if u, err := url.Parse(result.FinalURL); err == nil && result.FinalURL != "" {
+		return u
+	}
+	return domain
+}
+
+// redirectInScope reports whether the final URL of a redirected page still
+// belongs to the scope.
+func redirectInScope(result PageResult, scope Scope) bool {
+	if len(result.Redirects) == 0 {
+		return true
+	}
+	final, err := url.Parse(result.FinalURL)
+	return err == nil && scope.Contains(final)
+}
+
+// CollectPages reads the pages from the input channel and collects them into a slice.
+func CollectPages(pages <-chan *Page) []*Page {
+	result := []*Page{}
+	for page := range pages {
+		result = append(result, page)
 	}
 	return result
 }
@@ -76,3 +322,16 @@ func MapToList(links map[string]bool) []string {
 	}
 	return result
 }
+
+// countingReader is an io.Reader counting the bytes read from another reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read reads from the underlying reader and counts the bytes read.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
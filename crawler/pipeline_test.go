@@ -1,9 +1,14 @@
 package crawler_test
 
 import (
+	"context"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	crawler "github.com/paconte/gocrawler/crawler"
 
@@ -29,9 +34,15 @@ func TestDownload(t *testing.T) {
 		httpmock.NewStringResponder(status, fileContent))
 
 	// Test the function
-	out := crawler.Download(url)
-	response := <-out
-
+	out := crawler.Download(context.Background(), crawler.NewHTTPFetcher(nil), url)
+	page := <-out
+	response := page.Response
+
+	assert.Nil(t, page.Result.Err)
+	assert.Equal(t, url, page.Result.URL)
+	assert.Equal(t, url, page.Result.FinalURL)
+	assert.Equal(t, status, page.Result.Status)
+	assert.Equal(t, 1, page.Result.Attempts)
 	if response.StatusCode != status {
 		t.Errorf("Download(%q) = %v, want %v", url, response.StatusCode, 200)
 	}
@@ -62,9 +73,11 @@ func TestParse(t *testing.T) {
 		httpmock.NewStringResponder(status, fileContent))
 
 	// Test the function
-	out := crawler.Parse(crawler.Download(url))
-	node := <-out
-	_ = html.Node(*node) // Check if node is a html.Node
+	out := crawler.Parse(context.Background(), crawler.Download(context.Background(), crawler.NewHTTPFetcher(nil), url), 0)
+	page := <-out
+	_ = html.Node(*page.Doc) // Check if node is a html.Node
+	assert.Equal(t, int64(len(fileContent)), page.Result.Size)
+	assert.Greater(t, page.Result.Duration, time.Duration(0))
 }
 
 func TestExtract(t *testing.T) {
@@ -88,12 +101,12 @@ func TestExtract(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error reading file: %v", err)
 	}
-	out := crawler.Extract(crawler.Parse(crawler.Download(baseUrl)), parsedURL)
+	out := crawler.Extract(context.Background(), crawler.Parse(context.Background(), crawler.Download(context.Background(), crawler.NewHTTPFetcher(nil), baseUrl), 0), crawler.Scope{Root: parsedURL}, DefaultExtractor(t))
 
 	// Check the result
 	result := []string{}
-	for link := range out {
-		result = append(result, link)
+	for page := range out {
+		result = append(result, crawler.MapToList(page.Links)...)
 	}
 	expected := TargetLinks
 	for _, link := range expected {
@@ -102,15 +115,104 @@ func TestExtract(t *testing.T) {
 	}
 }
 
-func TestCollectMap(t *testing.T) {
-	ch := make(chan string, 2*len(TargetLinks))
+func TestNormalize(t *testing.T) {
+	root, _ := url.Parse("http://www.example.com")
+	in := make(chan *crawler.Page, 1)
+	in <- &crawler.Page{Links: map[string]bool{
+		"http://www.example.com/A":      true,
+		"http://WWW.example.com:80/A#b": true,
+		"http://www.example.com/B/":     true,
+		"http://www.example.com/":       true,
+	}}
+	close(in)
+
+	page := <-crawler.Normalize(context.Background(), in, crawler.Canonicalizer{}, root)
+	assert.ElementsMatch(t, []string{"http://www.example.com/A", "http://www.example.com/B/"}, crawler.MapToList(page.Links))
+}
+
+func TestDownloadErrors(t *testing.T) {
+	// Activate httpmock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Failed downloads are reported instead of dropped
+	out := crawler.Parse(context.Background(),
+		crawler.Download(context.Background(), crawler.NewHTTPFetcher(nil), "https://parserdigital.com/", "A"), 0)
+	pages := crawler.CollectPages(out)
+	assert.Equal(t, 2, len(pages))
+	for _, page := range pages {
+		assert.NotNil(t, page.Result.Err)
+		assert.Equal(t, 0, page.Result.Status)
+		assert.Nil(t, page.Doc)
+	}
+}
+
+// closeRecorder is a response body recording whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestParseBody(t *testing.T) {
+	page := "<html><body><a href=\"/A\">A</a></body></html>"
+	tests := []struct {
+		contentType string
+		content     string
+		maxBodySize int64
+		parsed      bool
+		err         error
+	}{
+		{"text/html; charset=utf-8", page, 0, true, nil},
+		{"application/xhtml+xml", page, 0, true, nil},
+		{"", page, 0, true, nil},
+		{"", "plain text", 0, false, nil},
+		{"image/png", page, 0, false, nil},
+		{"application/pdf", page, 0, false, nil},
+		{"text/html", page, int64(len(page)), true, nil},
+		{"text/html", page, 10, true, crawler.ErrBodyTooLarge},
+	}
+
+	for _, tt := range tests {
+		body := &closeRecorder{Reader: strings.NewReader(tt.content)}
+		in := make(chan *crawler.Page, 1)
+		in <- &crawler.Page{
+			Result:   crawler.PageResult{ContentType: tt.contentType},
+			Response: &http.Response{StatusCode: 200, Body: body},
+		}
+		close(in)
+
+		page := <-crawler.Parse(context.Background(), in, tt.maxBodySize)
+		assert.True(t, body.closed)
+		assert.Equal(t, tt.parsed, page.Doc != nil, "Parse() of %q", tt.contentType)
+		assert.Equal(t, tt.err, page.Result.Err)
+		if tt.maxBodySize > 0 {
+			assert.LessOrEqual(t, page.Result.Size, tt.maxBodySize)
+		}
+	}
+}
+
+func TestIsHTML(t *testing.T) {
+	assert.True(t, crawler.IsHTML("text/html"))
+	assert.True(t, crawler.IsHTML("TEXT/HTML; charset=utf-8"))
+	assert.True(t, crawler.IsHTML("application/xhtml+xml"))
+	assert.False(t, crawler.IsHTML("text/plain"))
+	assert.False(t, crawler.IsHTML("image/jpeg"))
+	assert.False(t, crawler.IsHTML(""))
+}
+
+func TestCollectPages(t *testing.T) {
+	ch := make(chan *crawler.Page, len(TargetLinks))
 	for _, link := range TargetLinks {
-		ch <- link
-		ch <- link
+		ch <- &crawler.Page{Result: crawler.PageResult{URL: link}}
 	}
 	close(ch)
 
-	result := crawler.CollectMap(ch)
+	result := crawler.CollectPages(ch)
 	assert.Equal(t, len(TargetLinks), len(result))
 }
 
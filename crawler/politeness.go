@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Politeness represents the per-host politeness settings of a crawl.
+// The zero value applies no throttling.
+type Politeness struct {
+	RequestsPerSecond float64       // Maximum requests per second to a host, 0 for no limit
+	Delay             time.Duration // Minimum delay between two requests to a host
+	MaxConnsPerHost   int           // Maximum concurrent requests to a host, 0 for no limit
+
+	// CrawlDelay optionally returns a delay requested by the host of the link,
+	// such as Robots.CrawlDelay. It is used when longer than Delay.
+	CrawlDelay func(ctx context.Context, link string) time.Duration
+}
+
+// interval returns the minimum time between two requests to the host of the link.
+func (p Politeness) interval(ctx context.Context, link string) time.Duration {
+	interval := p.Delay
+	if p.CrawlDelay != nil {
+		if delay := p.CrawlDelay(ctx, link); delay > interval {
+			interval = delay
+		}
+	}
+	if p.RequestsPerSecond > 0 {
+		if rate := time.Duration(float64(time.Second) / p.RequestsPerSecond); rate > interval {
+			interval = rate
+		}
+	}
+	return interval
+}
+
+// PoliteFetcher is a Fetcher that throttles the requests of another Fetcher
+// per host according to the Politeness settings.
+type PoliteFetcher struct {
+	fetcher    Fetcher
+	politeness Politeness
+	mutex      sync.Mutex
+	hosts      map[string]*hostLimiter
+}
+
+// hostLimiter keeps the throttling state of a single host.
+type hostLimiter struct {
+	conns chan struct{} // Semaphore of concurrent requests, nil for no limit
+	next  time.Time     // Earliest time of the next request
+}
+
+// NewPoliteFetcher creates a new PoliteFetcher wrapping the given fetcher.
+func NewPoliteFetcher(fetcher Fetcher, politeness Politeness) *PoliteFetcher {
+	return &PoliteFetcher{
+		fetcher:    fetcher,
+		politeness: politeness,
+		hosts:      map[string]*hostLimiter{},
+	}
+}
+
+// Fetch waits until the host of the URL can receive a new request and then
+// fetches it with the wrapped fetcher. The connection slot of the host is held
+// until the body of the response is closed.
+// It returns the context error if the context is cancelled while waiting.
+func (f *PoliteFetcher) Fetch(ctx context.Context, link string) (*http.Response, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	host := f.host(u.Host)
+
+	// Acquire a connection slot
+	release := func() {}
+	if host.conns != nil {
+		select {
+		case host.conns <- struct{}{}:
+			var once sync.Once
+			release = func() { once.Do(func() { <-host.conns }) }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Wait for the next request slot, giving it back if the wait is cancelled
+	interval := f.politeness.interval(ctx, link)
+	if slot := f.reserve(host, interval); time.Until(slot) > 0 {
+		timer := time.NewTimer(time.Until(slot))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			f.cancel(host, slot, interval)
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	resp, err := f.fetcher.Fetch(ctx, link)
+	if err != nil || resp == nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody is a response body releasing the connection slot of its host
+// when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body and releases the connection slot.
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// host returns the limiter of the given host, creating it if needed.
+func (f *PoliteFetcher) host(name string) *hostLimiter {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	host, ok := f.hosts[name]
+	if !ok {
+		host = &hostLimiter{}
+		if f.politeness.MaxConnsPerHost > 0 {
+			host.conns = make(chan struct{}, f.politeness.MaxConnsPerHost)
+		}
+		f.hosts[name] = host
+	}
+	return host
+}
+
+// reserve books the next request slot of the host, keeping the given interval
+// until the following one, and returns the time of the slot.
+func (f *PoliteFetcher) reserve(host *hostLimiter, interval time.Duration) time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := time.Now()
+	if host.next.Before(now) {
+		host.next = now
+	}
+	slot := host.next
+	host.next = host.next.Add(interval)
+	return slot
+}
+
+// cancel gives back the request slot of an abandoned wait, unless a later slot
+// was booked in the meantime.
+func (f *PoliteFetcher) cancel(host *hostLimiter, slot time.Time, interval time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if host.next.Equal(slot.Add(interval)) {
+		host.next = slot
+	}
+}
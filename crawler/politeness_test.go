@@ -0,0 +1,131 @@
+package crawler_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// okFetcher is a Fetcher returning an empty page for every URL.
+var okFetcher = crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+})
+
+func TestPoliteFetcherDelay(t *testing.T) {
+	tests := []struct {
+		politeness crawler.Politeness
+		links      []string
+		minimum    time.Duration
+		maximum    time.Duration
+	}{
+		{crawler.Politeness{}, []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"}, 0, 20 * time.Millisecond},
+		{crawler.Politeness{Delay: 20 * time.Millisecond}, []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"}, 40 * time.Millisecond, time.Second},
+		{crawler.Politeness{RequestsPerSecond: 50}, []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"}, 40 * time.Millisecond, time.Second},
+		{crawler.Politeness{Delay: 50 * time.Millisecond}, []string{"http://a.com/1", "http://b.com/1", "http://c.com/1"}, 0, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		fetcher := crawler.NewPoliteFetcher(okFetcher, tt.politeness)
+		start := time.Now()
+		for _, link := range tt.links {
+			_, err := fetcher.Fetch(context.Background(), link)
+			assert.Nil(t, err)
+		}
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, tt.minimum)
+		assert.Less(t, elapsed, tt.maximum)
+	}
+}
+
+// countingBody is a response body counting the open bodies in running.
+type countingBody struct {
+	io.Reader
+	close func()
+}
+
+// Close calls the close function.
+func (b countingBody) Close() error {
+	b.close()
+	return nil
+}
+
+func TestPoliteFetcherMaxConnsPerHost(t *testing.T) {
+	// A request runs until its body is closed
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	slow := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		return &http.Response{StatusCode: 200, Body: countingBody{strings.NewReader(""), func() {
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+		}}}, nil
+	})
+
+	fetcher := crawler.NewPoliteFetcher(slow, crawler.Politeness{MaxConnsPerHost: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := fetcher.Fetch(context.Background(), "http://a.com/")
+			if assert.Nil(t, err) {
+				time.Sleep(10 * time.Millisecond)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestPoliteFetcherCancelled(t *testing.T) {
+	fetcher := crawler.NewPoliteFetcher(okFetcher, crawler.Politeness{Delay: time.Hour})
+	_, err := fetcher.Fetch(context.Background(), "http://a.com/1")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(ctx, "http://a.com/2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The slot of a cancelled wait is given back
+	fetcher = crawler.NewPoliteFetcher(okFetcher, crawler.Politeness{Delay: 100 * time.Millisecond})
+	start := time.Now()
+	_, err = fetcher.Fetch(context.Background(), "http://a.com/1")
+	assert.Nil(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(ctx, "http://a.com/2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = fetcher.Fetch(context.Background(), "http://a.com/3")
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestPoliteFetcherCrawlDelay(t *testing.T) {
+	calls := 0
+	robots := crawler.NewRobots(NewRobotsFetcher("User-agent: *\nCrawl-delay: 0.02", &calls), crawler.DefaultUserAgent)
+	fetcher := crawler.NewPoliteFetcher(okFetcher, crawler.Politeness{CrawlDelay: robots.CrawlDelay})
+
+	start := time.Now()
+	for _, link := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"} {
+		_, err := fetcher.Fetch(context.Background(), link)
+		assert.Nil(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
@@ -0,0 +1,128 @@
+package crawler_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// NewRedirectFetcher returns an HTTPFetcher whose client answers with the given
+// redirects, from source to target URL, and serves the HtmlFiles tree. Any
+// other URL is served the level 2 page A.
+func NewRedirectFetcher(t *testing.T, redirects map[string]string) crawler.Fetcher {
+	transport := httpmock.NewMockTransport()
+	transport.RegisterNoResponder(
+		httpmock.NewStringResponder(200, LoadFileAsString(t, "testdata/treeLevel2A.html")))
+	for link, file := range HtmlFiles {
+		transport.RegisterResponder("GET", link,
+			httpmock.NewStringResponder(200, LoadFileAsString(t, file)))
+	}
+	for source, target := range redirects {
+		resp := httpmock.NewStringResponse(http.StatusMovedPermanently, "")
+		resp.Header.Set("Location", target)
+		transport.RegisterResponder("GET", source, httpmock.ResponderFromResponse(resp))
+	}
+	return crawler.NewHTTPFetcher(&http.Client{Transport: transport})
+}
+
+func TestDownloadRedirects(t *testing.T) {
+	fetcher := NewRedirectFetcher(t, map[string]string{
+		"http://www.parserdigital.com/old":   "http://www.parserdigital.com/older",
+		"http://www.parserdigital.com/older": "http://www.parserdigital.com/A",
+		"http://www.parserdigital.com/loop1": "http://www.parserdigital.com/loop2",
+		"http://www.parserdigital.com/loop2": "http://www.parserdigital.com/loop1",
+	})
+
+	tests := []struct {
+		link      string
+		finalURL  string
+		redirects []string
+		err       error
+	}{
+		{"http://www.parserdigital.com/A", "http://www.parserdigital.com/A", nil, nil},
+		{"http://www.parserdigital.com/old", "http://www.parserdigital.com/A", []string{
+			"http://www.parserdigital.com/older",
+			"http://www.parserdigital.com/A",
+		}, nil},
+		{"http://www.parserdigital.com/loop1", "", []string{
+			"http://www.parserdigital.com/loop2",
+			"http://www.parserdigital.com/loop1",
+		}, crawler.ErrRedirectLoop},
+	}
+
+	for _, tt := range tests {
+		page := <-crawler.Download(context.Background(), fetcher, tt.link)
+		assert.Equal(t, tt.finalURL, page.Result.FinalURL)
+		assert.Equal(t, tt.redirects, page.Result.Redirects)
+		assert.ErrorIs(t, page.Result.Err, tt.err)
+	}
+}
+
+func TestDownloadTooManyRedirects(t *testing.T) {
+	redirects := map[string]string{}
+	for i := 0; i <= crawler.DefaultMaxRedirects; i++ {
+		redirects["http://www.parserdigital.com/"+string(rune('a'+i))] = "http://www.parserdigital.com/" + string(rune('a'+i+1))
+	}
+	fetcher := NewRedirectFetcher(t, redirects)
+
+	page := <-crawler.Download(context.Background(), fetcher, "http://www.parserdigital.com/a")
+	assert.ErrorIs(t, page.Result.Err, crawler.ErrTooManyRedirects)
+	assert.Equal(t, crawler.DefaultMaxRedirects, len(page.Result.Redirects))
+}
+
+func TestExtractRedirectOutOfScope(t *testing.T) {
+	fetcher := NewRedirectFetcher(t, map[string]string{
+		"http://www.parserdigital.com/A": "http://www.example.com/A",
+	})
+	domain, _ := url.Parse("http://www.parserdigital.com")
+	out := crawler.Extract(context.Background(), crawler.Parse(context.Background(),
+		crawler.Download(context.Background(), fetcher, "http://www.parserdigital.com/A"), 0), crawler.Scope{Root: domain}, DefaultExtractor(t))
+	page := <-out
+	assert.Equal(t, crawler.ErrRedirectOutOfScope, page.Result.Err)
+	assert.Empty(t, page.Links)
+}
+
+func TestRedirectStopsAtScope(t *testing.T) {
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://www.parserdigital.com",
+		httpmock.NewStringResponder(200, `<html><a href="/A">A</a><a href="/B">B</a></html>`))
+	transport.RegisterResponder("GET", "http://www.parserdigital.com/robots.txt",
+		httpmock.NewStringResponder(200, "User-agent: *\nDisallow: /private"))
+	transport.RegisterResponder("GET", "=~^http://www\\.example\\.com/",
+		httpmock.NewStringResponder(200, "<html></html>"))
+	transport.RegisterResponder("GET", "http://www.parserdigital.com/private",
+		httpmock.NewStringResponder(200, "<html></html>"))
+	for source, target := range map[string]string{
+		"http://www.parserdigital.com/A":     "http://www.parserdigital.com/moved",
+		"http://www.parserdigital.com/moved": "http://www.example.com/A",
+		"http://www.parserdigital.com/B":     "/private",
+	} {
+		resp := httpmock.NewStringResponse(http.StatusMovedPermanently, "")
+		resp.Header.Set("Location", target)
+		transport.RegisterResponder("GET", source, httpmock.ResponderFromResponse(resp))
+	}
+	fetcher := crawler.NewHTTPFetcher(&http.Client{Transport: transport})
+	root, _ := url.Parse("http://www.parserdigital.com")
+	opts := crawler.Options{Fetcher: fetcher, Robots: crawler.NewRobots(fetcher, crawler.DefaultUserAgent)}
+
+	// The hops outside the scope or disallowed are recorded but not downloaded
+	results := map[string]crawler.PageResult{}
+	for _, result := range crawler.NewRecursive(root, opts).Run(context.Background()) {
+		results[result.URL] = result
+	}
+	assert.ErrorIs(t, results["http://www.parserdigital.com/A"].Err, crawler.ErrRedirectOutOfScope)
+	assert.Equal(t, []string{"http://www.parserdigital.com/moved", "http://www.example.com/A"},
+		results["http://www.parserdigital.com/A"].Redirects)
+	assert.ErrorIs(t, results["http://www.parserdigital.com/B"].Err, crawler.ErrRedirectDisallowed)
+	assert.Equal(t, []string{"http://www.parserdigital.com/private"}, results["http://www.parserdigital.com/B"].Redirects)
+
+	calls := transport.GetCallCountInfo()
+	assert.Zero(t, calls["GET =~^http://www\\.example\\.com/"])
+	assert.Zero(t, calls["GET http://www.parserdigital.com/private"])
+}
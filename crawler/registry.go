@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownStrategy is returned by Run when no strategy is registered under
+// the requested name.
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// StrategyFactory creates a strategy crawling the root URL with the given
+// options.
+type StrategyFactory func(url *url.URL, opts Options) Strategy
+
+// StrategyInfo describes a registered strategy.
+type StrategyInfo struct {
+	Name        string // Name the strategy is run with
+	Description string // One line description of the strategy
+}
+
+// registeredStrategy is an entry of the strategy registry.
+type registeredStrategy struct {
+	info    StrategyInfo
+	factory StrategyFactory
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]registeredStrategy{}
+)
+
+func init() {
+	RegisterStrategy("OneLevel", "Collects the URLs linked from the root URL without fetching them",
+		func(url *url.URL, opts Options) Strategy { return NewOneLevel(url, opts) })
+	RegisterStrategy("Recursive", "Crawls the URLs recursively one at a time, breadth first",
+		func(url *url.URL, opts Options) Strategy { return NewRecursive(url, opts) })
+	RegisterStrategy("RecursiveWithLimits", "Recursive with the milliseconds and requests limits",
+		func(url *url.URL, opts Options) Strategy { return NewRecursiveWithLimits(url, opts) })
+	RegisterStrategy("RecursiveParallel", "Crawls the URLs recursively with concurrent workers, breadth first",
+		func(url *url.URL, opts Options) Strategy { return NewRecursiveParallel(url, opts) })
+	RegisterStrategy("RecursiveParallelWithLimits", "RecursiveParallel with the milliseconds and requests limits",
+		func(url *url.URL, opts Options) Strategy { return NewRecursiveParallelWithLimits(url, opts) })
+	RegisterStrategy("BestFirst", "Crawls first the URLs with the highest score, by inbound links by default",
+		func(url *url.URL, opts Options) Strategy { return NewBestFirst(url, opts) })
+	RegisterStrategy("BestFirstWithLimits", "BestFirst with the milliseconds and requests limits",
+		func(url *url.URL, opts Options) Strategy { return NewBestFirstWithLimits(url, opts) })
+	RegisterStrategy("DepthFirst", "Follows each branch to the bottom before backtracking, with optional per-branch caps",
+		func(url *url.URL, opts Options) Strategy { return NewDepthFirst(url, opts) })
+	RegisterStrategy("DepthFirstWithLimits", "DepthFirst with the milliseconds and requests limits",
+		func(url *url.URL, opts Options) Strategy { return NewDepthFirstWithLimits(url, opts) })
+}
+
+// RegisterStrategy makes a strategy available to Run under the given name, so
+// that strategies defined outside this package can be run by name.
+// It panics if the name is empty or already registered, or if the factory is
+// nil.
+func RegisterStrategy(name, description string, factory StrategyFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if name == "" {
+		panic("crawler: RegisterStrategy with an empty name")
+	}
+	if factory == nil {
+		panic("crawler: RegisterStrategy factory is nil for " + name)
+	}
+	if _, ok := registry[name]; ok {
+		panic("crawler: RegisterStrategy called twice for " + name)
+	}
+	registry[name] = registeredStrategy{
+		info:    StrategyInfo{Name: name, Description: description},
+		factory: factory,
+	}
+}
+
+// LookupStrategy returns the description of the strategy registered under the
+// given name, or false if there is none.
+func LookupStrategy(name string) (StrategyInfo, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	strategy, ok := registry[name]
+	return strategy.info, ok
+}
+
+// Strategies returns the descriptions of the registered strategies sorted by
+// name.
+func Strategies() []StrategyInfo {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	infos := make([]StrategyInfo, 0, len(registry))
+	for _, strategy := range registry {
+		infos = append(infos, strategy.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
@@ -0,0 +1,67 @@
+package crawler_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticStrategy is a strategy returning a fixed result.
+type staticStrategy struct {
+	url *url.URL
+}
+
+func (s staticStrategy) Run(ctx context.Context) []crawler.PageResult {
+	return []crawler.PageResult{{URL: s.url.String(), Status: 200}}
+}
+
+// RegisterStatic registers the staticStrategy under the given name, unless it
+// is already registered by a previous run of the tests.
+func RegisterStatic(name string) {
+	if _, ok := crawler.LookupStrategy(name); !ok {
+		crawler.RegisterStrategy(name, "Returns the root URL", func(url *url.URL, opts crawler.Options) crawler.Strategy {
+			return staticStrategy{url: url}
+		})
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStatic("Static")
+
+	info, ok := crawler.LookupStrategy("Static")
+	assert.True(t, ok)
+	assert.Equal(t, crawler.StrategyInfo{Name: "Static", Description: "Returns the root URL"}, info)
+	assert.Contains(t, crawler.Strategies(), info)
+
+	results, err := crawler.Run(context.Background(), "http://www.example.com", "Static", emptyOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, []crawler.PageResult{{URL: "http://www.example.com", Status: 200}}, results)
+
+	assert.Panics(t, func() {
+		crawler.RegisterStrategy("Static", "", func(url *url.URL, opts crawler.Options) crawler.Strategy { return nil })
+	})
+	assert.Panics(t, func() { crawler.RegisterStrategy("Nil", "", nil) })
+	assert.Panics(t, func() {
+		crawler.RegisterStrategy("", "", func(url *url.URL, opts crawler.Options) crawler.Strategy { return nil })
+	})
+}
+
+func TestStrategies(t *testing.T) {
+	names := []string{}
+	for _, info := range crawler.Strategies() {
+		names = append(names, info.Name)
+		assert.NotEmpty(t, info.Description)
+	}
+	assert.Subset(t, names, []string{"BestFirst", "DepthFirst", "OneLevel", "Recursive", "RecursiveParallel",
+		"RecursiveParallelWithLimits", "RecursiveWithLimits"})
+	assert.IsIncreasing(t, names)
+
+	_, ok := crawler.LookupStrategy("Something")
+	assert.False(t, ok)
+	_, err := crawler.Run(context.Background(), "http://www.example.com", "Something", emptyOptions)
+	assert.ErrorIs(t, err, crawler.ErrUnknownStrategy)
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// PageResult represents the outcome of crawling a single URL.
+type PageResult struct {
+	URL          string        // Requested URL
+	FinalURL     string        // URL of the final response, after redirects
+	Redirects    []string      // URLs of the redirect hops, ending with the FinalURL or the hop that failed
+	Status       int           // HTTP status code, 0 if the URL was not fetched
+	ContentType  string        // Content-Type header of the response
+	Size         int64         // Size in bytes of the response body read
+	Duration     time.Duration // Time spent downloading and parsing the page
+	Attempts     int           // Number of fetch attempts
+	Err          error         // Fetch or parse error, if any
+	Depth        int           // Distance in links from the root URL
+	Parent       string        // URL of the page the URL was found on, empty for the root
+	NoIndex      bool          // The page asked not to be indexed
+	NoFollow     bool          // The URL was only found on nofollow links and was not fetched
+	Canonical    string        // URL of the <link rel="canonical"> element of the page, if any
+	CanonicalErr error         // Problem with the canonical URL, such as ErrCanonicalNotOK
+	Duplicates   []string      // URLs declaring this URL as their canonical, merged by MergeCanonical
+	Links        []Link        // Links found on the page, in or out of the scope
+}
+
+// Page represents a page flowing through the stages of the pipeline.
+// Each stage fills in its own fields and records its failures in the result.
+type Page struct {
+	Result   PageResult
+	Response *http.Response  // Set by Download
+	Doc      *html.Node      // Set by Parse
+	Links    map[string]bool // Set by Extract
+	NoFollow map[string]bool // Set by Extract, links not to follow
+	start    time.Time
+}
+
+// redirectChain returns the URLs of the redirect hops that led to the
+// response, in order, or nil if the response was not redirected.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
+	}
+	return chain
+}
+
+// stoppedRedirectChain returns the URLs of the redirect hops of a redirect
+// chain stopped by the client, given its last response, ending with the hop
+// that was not followed.
+func stoppedRedirectChain(resp *http.Response) []string {
+	chain := redirectChain(resp)
+	location := resp.Header.Get("Location")
+	if resp.Request == nil || resp.Request.URL == nil || location == "" {
+		return chain
+	}
+	if next, err := resp.Request.URL.Parse(location); err == nil {
+		chain = append(chain, next.String())
+	}
+	return chain
+}
+
+// SortResults sorts the results by URL.
+func SortResults(results []PageResult) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	})
+}
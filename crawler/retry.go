@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy represents how failed requests are retried.
+// The zero value does not retry.
+type RetryPolicy struct {
+	MaxAttempts int           // Maximum attempts per URL, 1 or less for no retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled on every retry
+	MaxDelay    time.Duration // Maximum delay between two attempts, 0 for no limit
+}
+
+// retryableStatus contains the HTTP status codes that are worth retrying.
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// transientError reports whether the fetch error is a transient network error
+// worth retrying: a timeout, a connection reset or refused, or a connection
+// closed before the end of the response. The errors are unwrapped, including
+// the *url.Error of the http.Client.
+func transientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns the delay before the given retry, starting at 1, using an
+// exponential backoff with jitter.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Wait between half and the whole delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// RetryFetcher is a Fetcher that retries the transient failures of another
+// Fetcher according to a RetryPolicy. Transient network errors and the status
+// codes 408, 429, 500, 502, 503 and 504 are retried, honoring the Retry-After
+// header. Other errors, such as redirect loops, are returned at once.
+// The attempts are reported in the results of the crawl even when the
+// RetryFetcher is wrapped by other fetchers, such as a PoliteFetcher.
+type RetryFetcher struct {
+	fetcher  Fetcher
+	policy   RetryPolicy
+	mutex    sync.Mutex
+	attempts map[string]int
+}
+
+// NewRetryFetcher creates a new RetryFetcher wrapping the given fetcher.
+func NewRetryFetcher(fetcher Fetcher, policy RetryPolicy) *RetryFetcher {
+	return &RetryFetcher{
+		fetcher:  fetcher,
+		policy:   policy,
+		attempts: map[string]int{},
+	}
+}
+
+// Fetch fetches the URL with the wrapped fetcher, retrying transient failures.
+// The last response or error is returned when all the attempts fail.
+func (f *RetryFetcher) Fetch(ctx context.Context, link string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		f.mutex.Lock()
+		f.attempts[link] = attempt
+		f.mutex.Unlock()
+		if counter, ok := ctx.Value(attemptsKey{}).(*int); ok {
+			*counter = attempt
+		}
+
+		resp, err := f.fetcher.Fetch(ctx, link)
+		if attempt >= f.policy.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !transientError(err) {
+				return resp, err
+			}
+			delay = f.policy.backoff(attempt)
+		case retryableStatus[resp.StatusCode]:
+			delay = f.policy.backoff(attempt)
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+				if f.policy.MaxDelay > 0 && delay > f.policy.MaxDelay {
+					delay = f.policy.MaxDelay
+				}
+			}
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attemptsKey is the context key of the attempts counter of a fetch.
+type attemptsKey struct{}
+
+// withAttempts returns a copy of the context in which the RetryFetchers record
+// the number of attempts of the fetch in attempts, wherever they are in the
+// chain of fetchers.
+func withAttempts(ctx context.Context, attempts *int) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, attempts)
+}
+
+// Attempts returns the number of attempts made to fetch the URL, or zero if it
+// was never fetched.
+func (f *RetryFetcher) Attempts(link string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.attempts[link]
+}
+
+// retryAfter parses the Retry-After header of the response, given either in
+// seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
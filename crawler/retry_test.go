@@ -0,0 +1,131 @@
+package crawler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NewSequenceFetcher returns a Fetcher answering the successive requests with
+// the given status codes, where 0 stands for a connection reset and -1 for a
+// redirect loop.
+func NewSequenceFetcher(header http.Header, status ...int) crawler.Fetcher {
+	calls := 0
+	return crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		code := status[calls]
+		if calls < len(status)-1 {
+			calls++
+		}
+		switch code {
+		case 0:
+			return nil, &url.Error{Op: "Get", URL: link, Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}
+		case -1:
+			return nil, &url.Error{Op: "Get", URL: link, Err: crawler.ErrRedirectLoop}
+		}
+		return &http.Response{
+			StatusCode: code,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+}
+
+func TestRetryFetcher(t *testing.T) {
+	link := "http://a.com/"
+	policy := crawler.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	tests := []struct {
+		status   []int
+		policy   crawler.RetryPolicy
+		expected int // Final status, 0 for an error
+		attempts int
+	}{
+		{[]int{200}, policy, 200, 1},
+		{[]int{0, 200}, policy, 200, 2},
+		{[]int{503, 429, 200}, policy, 200, 3},
+		{[]int{503, 503, 503, 200}, policy, 503, 3},
+		{[]int{0, 0, 0, 200}, policy, 0, 3},
+		{[]int{404, 200}, policy, 404, 1},
+		{[]int{0, 200}, crawler.RetryPolicy{}, 0, 1},
+		{[]int{-1, 200}, crawler.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		fetcher := crawler.NewRetryFetcher(NewSequenceFetcher(nil, tt.status...), tt.policy)
+		resp, err := fetcher.Fetch(context.Background(), link)
+		if tt.expected == 0 {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, resp.StatusCode)
+		}
+		assert.Equal(t, tt.attempts, fetcher.Attempts(link))
+	}
+}
+
+func TestRetryFetcherRetryAfter(t *testing.T) {
+	link := "http://a.com/"
+	tests := []struct {
+		header  string
+		minimum time.Duration
+		maximum time.Duration
+	}{
+		{"0", 0, 20 * time.Millisecond},
+		{"1", time.Second, 2 * time.Second},
+		{"3600", 50 * time.Millisecond, time.Second}, // Capped by the MaxDelay
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{"Retry-After": []string{tt.header}}
+		policy := crawler.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+		if tt.minimum >= time.Second {
+			policy.MaxDelay = 0
+		}
+		fetcher := crawler.NewRetryFetcher(NewSequenceFetcher(header, 429, 200), policy)
+
+		start := time.Now()
+		resp, err := fetcher.Fetch(context.Background(), link)
+		elapsed := time.Since(start)
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.GreaterOrEqual(t, elapsed, tt.minimum)
+		assert.Less(t, elapsed, tt.maximum)
+	}
+}
+
+func TestRetryFetcherCancelled(t *testing.T) {
+	policy := crawler.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+	fetcher := crawler.NewRetryFetcher(NewSequenceFetcher(nil, 503, 200), policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := fetcher.Fetch(ctx, "http://a.com/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryFetcherAttemptsInResults(t *testing.T) {
+	policy := crawler.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	fetcher := crawler.NewRetryFetcher(NewSequenceFetcher(nil, 0, 503, 200), policy)
+
+	page := <-crawler.Download(context.Background(), fetcher, "http://a.com/")
+	assert.Nil(t, page.Result.Err)
+	assert.Equal(t, 200, page.Result.Status)
+	assert.Equal(t, 3, page.Result.Attempts)
+
+	// The attempts are reported through the fetchers wrapping the RetryFetcher
+	fetcher = crawler.NewRetryFetcher(NewSequenceFetcher(nil, 503, 503, 200), policy)
+	page = <-crawler.Download(context.Background(), crawler.NewPoliteFetcher(fetcher, crawler.Politeness{}), "http://a.com/")
+	assert.Equal(t, 200, page.Result.Status)
+	assert.Equal(t, 3, page.Result.Attempts)
+}
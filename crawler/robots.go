@@ -0,0 +1,241 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is the user agent the crawler identifies itself with.
+const DefaultUserAgent = "gocrawler"
+
+// Robots fetches, caches and evaluates the robots.txt files of the crawled hosts.
+// Each robots.txt file is fetched only once. Hosts whose robots.txt cannot be
+// fetched are crawled without restrictions.
+type Robots struct {
+	fetcher   Fetcher
+	userAgent string
+	mutex     sync.Mutex
+	hosts     map[string]*robotsEntry // Entries by scheme and host
+}
+
+// robotsEntry is the cache entry of the robots.txt file of a host.
+type robotsEntry struct {
+	mutex sync.Mutex
+	done  bool // Whether the rules are cached
+	rules *robotsRules
+}
+
+// robotsRules represents the rules of the group of a robots.txt file that
+// applies to the crawler, along with the sitemaps listed in the file.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+// robotsRule represents a single Allow or Disallow rule.
+type robotsRule struct {
+	allow   bool
+	length  int // Length of the path pattern, used to find the most specific rule
+	pattern *regexp.Regexp
+}
+
+// NewRobots creates a new Robots fetching the robots.txt files with the given
+// fetcher and matching the groups against the given user agent.
+func NewRobots(fetcher Fetcher, userAgent string) *Robots {
+	return &Robots{
+		fetcher:   fetcher,
+		userAgent: userAgent,
+		hosts:     map[string]*robotsEntry{},
+	}
+}
+
+// Allowed reports whether the robots.txt file of the link's host allows the
+// crawler to fetch the link.
+func (r *Robots) Allowed(ctx context.Context, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return r.rules(ctx, u).allowed(u.RequestURI())
+}
+
+// CrawlDelay returns the Crawl-delay of the robots.txt file of the link's host,
+// or zero if there is none.
+func (r *Robots) CrawlDelay(ctx context.Context, link string) time.Duration {
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0
+	}
+	return r.rules(ctx, u).crawlDelay
+}
+
+// Sitemaps returns the URLs of the Sitemap lines of the robots.txt file of the
+// link's host.
+func (r *Robots) Sitemaps(ctx context.Context, link string) []string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	return r.rules(ctx, u).sitemaps
+}
+
+// rules returns the rules of the URL's host, fetching its robots.txt file if
+// it is not cached yet.
+func (r *Robots) rules(ctx context.Context, u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	r.mutex.Lock()
+	entry, ok := r.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		r.hosts[key] = entry
+	}
+	r.mutex.Unlock()
+
+	// The rules fetched with a cancelled context are not cached, so that the
+	// next caller fetches the robots.txt file again with its own context
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.done {
+		return entry.rules
+	}
+	rules := &robotsRules{}
+	resp, err := r.fetcher.Fetch(withoutProbe(ctx), key+"/robots.txt")
+	if err == nil {
+		if resp.StatusCode == 200 {
+			rules = parseRobots(resp.Body, r.userAgent)
+		}
+		resp.Body.Close()
+	}
+	if ctx.Err() == nil {
+		entry.rules, entry.done = rules, true
+	}
+	return rules
+}
+
+// parseRobots parses a robots.txt file and returns the rules of the group that
+// best matches the user agent. Groups naming the product token of the user
+// agent, compared without case as in RFC 9309, take precedence over the "*"
+// group, and groups for the same user agent are merged.
+// The Sitemap lines, which do not belong to any group, are always returned.
+func parseRobots(body io.Reader, userAgent string) *robotsRules {
+	agent := productToken(userAgent)
+	specific, generic := &robotsRules{}, &robotsRules{}
+	sitemaps := []string{}
+	var matched, wildcard, hasSpecific bool
+	inAgents := false // Whether the previous line was a User-agent line
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "sitemap" {
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+			continue
+		}
+		if key == "user-agent" {
+			if !inAgents {
+				matched, wildcard = false, false
+			}
+			inAgents = true
+			name := productToken(value)
+			if name == "*" {
+				wildcard = true
+			} else if name != "" && name == agent {
+				matched, hasSpecific = true, true
+			}
+			continue
+		}
+		inAgents = false
+
+		var group *robotsRules
+		switch {
+		case matched:
+			group = specific
+		case wildcard:
+			group = generic
+		default:
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			if value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	specific.sitemaps, generic.sitemaps = sitemaps, sitemaps
+	if hasSpecific {
+		return specific
+	}
+	return generic
+}
+
+// productToken returns the lowercased product token of a user agent, the name
+// before its version and comments, such as "gocrawler" for "GoCrawler/1.0".
+func productToken(userAgent string) string {
+	token := strings.TrimSpace(userAgent)
+	if i := strings.IndexAny(token, "/ \t("); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// robotsPattern compiles a robots.txt path pattern, where "*" matches any
+// sequence of characters and a trailing "$" anchors the end of the path.
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed reports whether the rules allow the given path. The longest matching
+// rule wins, and Allow wins over Disallow when both have the same length.
+func (r *robotsRules) allowed(path string) bool {
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > length || (rule.length == length && rule.allow) {
+			allow, length = rule.allow, rule.length
+		}
+	}
+	return allow
+}
@@ -0,0 +1,189 @@
+package crawler_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// NewRobotsFetcher returns a Fetcher serving the given robots.txt content for
+// every host and counting the requests in calls.
+func NewRobotsFetcher(content string, calls *int) crawler.Fetcher {
+	return crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		*calls++
+		u, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		return NewContentFetcher(map[string]string{u.Scheme + "://" + u.Host + "/robots.txt": content}).Fetch(ctx, link)
+	})
+}
+
+const robotsTxt = `
+# Rules for every crawler
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search*q=
+Crawl-delay: 2
+
+User-agent: othercrawler
+Disallow: /
+
+User-agent: GoCrawler
+User-agent: anothercrawler
+Disallow: /admin
+Allow: /admin/login
+Crawl-delay: 0.5
+`
+
+func TestRobotsAllowed(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		link      string
+		expected  bool
+	}{
+		{"somecrawler", "http://a.com/", true},
+		{"somecrawler", "http://a.com/private/", false},
+		{"somecrawler", "http://a.com/private/page", false},
+		{"somecrawler", "http://a.com/private/public/page", true},
+		{"somecrawler", "http://a.com/file.pdf", false},
+		{"somecrawler", "http://a.com/file.pdf?x=1", true},
+		{"somecrawler", "http://a.com/docs/file.pdf", false},
+		{"somecrawler", "http://a.com/search?q=go", false},
+		{"somecrawler", "http://a.com/search?p=1&q=go", false},
+		{"somecrawler", "http://a.com/search", true},
+		{"somecrawler", "http://a.com/admin", true},
+		{"othercrawler", "http://a.com/", false},
+		{"othercrawler/1.0", "http://a.com/about", false},
+		{crawler.DefaultUserAgent, "http://a.com/private/", true},
+		{crawler.DefaultUserAgent, "http://a.com/admin", false},
+		{crawler.DefaultUserAgent, "http://a.com/admin/users", false},
+		{crawler.DefaultUserAgent, "http://a.com/admin/login", true},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		robots := crawler.NewRobots(NewRobotsFetcher(robotsTxt, &calls), tt.userAgent)
+		result := robots.Allowed(context.Background(), tt.link)
+		assert.Equal(t, tt.expected, result, "Allowed(%q) for %q", tt.link, tt.userAgent)
+	}
+}
+
+func TestRobotsProductToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"go", true},
+		{"c", true},
+		{"crawler", true},
+		{"gocrawlers", true},
+		{"gocrawler", false},
+		{"GOCRAWLER", false},
+		{"gocrawler/2.0", false},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		content := "User-agent: " + tt.name + "\nDisallow: /\n"
+		robots := crawler.NewRobots(NewRobotsFetcher(content, &calls), crawler.DefaultUserAgent)
+		assert.Equal(t, tt.expected, robots.Allowed(context.Background(), "http://a.com/page"), tt.name)
+	}
+}
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	calls := 0
+	robots := crawler.NewRobots(NewRobotsFetcher(robotsTxt, &calls), "somecrawler")
+	assert.Equal(t, 2*time.Second, robots.CrawlDelay(context.Background(), "http://a.com/"))
+
+	robots = crawler.NewRobots(NewRobotsFetcher(robotsTxt, &calls), crawler.DefaultUserAgent)
+	assert.Equal(t, 500*time.Millisecond, robots.CrawlDelay(context.Background(), "http://a.com/"))
+}
+
+func TestRobotsCache(t *testing.T) {
+	calls := 0
+	robots := crawler.NewRobots(NewRobotsFetcher(robotsTxt, &calls), crawler.DefaultUserAgent)
+	robots.Allowed(context.Background(), "http://a.com/1")
+	robots.Allowed(context.Background(), "http://a.com/2")
+	robots.CrawlDelay(context.Background(), "http://a.com/3")
+	assert.Equal(t, 1, calls)
+
+	robots.Allowed(context.Background(), "http://b.com/1")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRobotsCancelled(t *testing.T) {
+	calls := 0
+	fetcher := NewRobotsFetcher(robotsTxt, &calls)
+	robots := crawler.NewRobots(crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return fetcher.Fetch(ctx, link)
+	}), crawler.DefaultUserAgent)
+
+	// The robots.txt file missed with a cancelled context is fetched again
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, robots.Allowed(ctx, "http://a.com/admin/1"))
+	assert.False(t, robots.Allowed(context.Background(), "http://a.com/admin/1"))
+	assert.False(t, robots.Allowed(context.Background(), "http://a.com/admin/2"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRobotsMissing(t *testing.T) {
+	robots := crawler.NewRobots(NewFileFetcher(t, HtmlFiles), crawler.DefaultUserAgent)
+	assert.True(t, robots.Allowed(context.Background(), "http://www.parserdigital.com/A"))
+	assert.Equal(t, time.Duration(0), robots.CrawlDelay(context.Background(), "http://www.parserdigital.com/A"))
+}
+
+func TestStrategiesRobots(t *testing.T) {
+	calls := 0
+	robots := crawler.NewRobots(NewRobotsFetcher("User-agent: *\nDisallow: /B", &calls), crawler.DefaultUserAgent)
+	opts := crawler.Options{
+		Fetcher: NewFileFetcher(t, HtmlFiles),
+		Limits:  crawler.Limits{Milliseconds: 100 * 1000, Requests: 100},
+		Robots:  robots,
+	}
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	strategies := []crawler.Strategy{
+		crawler.NewRecursive(parsedUrl, opts),
+		crawler.NewRecursiveWithLimits(parsedUrl, opts),
+		crawler.NewRecursiveParallel(parsedUrl, opts),
+		crawler.NewRecursiveParallelWithLimits(parsedUrl, opts),
+	}
+	for _, strategy := range strategies {
+		result := strategy.Run(context.Background())
+		assert.Equal(t, 5, len(result))
+		assert.NotContains(t, URLs(result), "http://www.parserdigital.com/B")
+		assert.NotContains(t, URLs(result), "http://www.parserdigital.com/E")
+	}
+
+	result := crawler.NewOneLevel(parsedUrl, opts).Run(context.Background())
+	assert.Equal(t, []string{"http://www.parserdigital.com", "http://www.parserdigital.com/A"}, URLs(result))
+
+	// A disallowed root is never crawled
+	robots = crawler.NewRobots(NewRobotsFetcher("User-agent: *\nDisallow: /", &calls), crawler.DefaultUserAgent)
+	opts.Robots = robots
+	result = crawler.NewRecursive(parsedUrl, opts).Run(context.Background())
+	assert.Empty(t, result)
+}
+
+func TestRobotsSitemaps(t *testing.T) {
+	calls := 0
+	content := "Sitemap: http://www.example.com/a.xml\nUser-agent: gocrawler\nDisallow: /\nSitemap: http://www.example.com/b.xml\n"
+	robots := crawler.NewRobots(NewRobotsFetcher(content, &calls), crawler.DefaultUserAgent)
+
+	expected := []string{"http://www.example.com/a.xml", "http://www.example.com/b.xml"}
+	assert.Equal(t, expected, robots.Sitemaps(context.Background(), "http://www.example.com/page"))
+	assert.False(t, robots.Allowed(context.Background(), "http://www.example.com/page"))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,189 @@
+package crawler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// HostMode selects which hosts belong to the scope of a crawl.
+type HostMode int
+
+const (
+	// ExactHost accepts only the host of the root URL.
+	ExactHost HostMode = iota
+	// RegistrableDomain accepts any host under the registrable domain (eTLD+1)
+	// of the root URL, such as blog.example.com for www.example.com. An IP
+	// address has no registrable domain and only accepts itself.
+	RegistrableDomain
+	// AllowedHosts accepts the host of the root URL and the hosts of an
+	// explicit allowlist.
+	AllowedHosts
+)
+
+// hostModes maps the names of the host modes, as used by the CLI, to their values.
+var hostModes = map[string]HostMode{
+	"exact":  ExactHost,
+	"domain": RegistrableDomain,
+	"hosts":  AllowedHosts,
+}
+
+// ParseHostMode returns the host mode with the given name: "exact", "domain"
+// or "hosts".
+func ParseHostMode(name string) (HostMode, error) {
+	mode, ok := hostModes[name]
+	if !ok {
+		return ExactHost, fmt.Errorf("unknown scope mode %q", name)
+	}
+	return mode, nil
+}
+
+// Pattern is a regular expression or a glob matched against the URLs.
+type Pattern struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+// ParsePattern parses a pattern. The expressions prefixed with "re:" are
+// regular expressions, matching any part of the URL. The other ones are globs
+// matching the whole URL, where "*" matches any characters but "/", "**" any
+// characters and "?" a single character but "/".
+func ParsePattern(expr string) (*Pattern, error) {
+	source, ok := strings.CutPrefix(expr, "re:")
+	if !ok {
+		source = globToRegexp(expr)
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+	return &Pattern{expr: expr, re: re}, nil
+}
+
+// globToRegexp returns the anchored regular expression of a glob.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Match reports whether the link matches the pattern.
+func (p *Pattern) Match(link string) bool {
+	return p.re.MatchString(link)
+}
+
+// String returns the expression the pattern was parsed from.
+func (p *Pattern) String() string {
+	return p.expr
+}
+
+// Scope represents the URLs that belong to a crawl.
+// The zero value accepts only the host of the root URL.
+type Scope struct {
+	Root       *url.URL   // Root URL of the crawl, set by the strategies
+	Mode       HostMode   // Hosts accepted in the crawl
+	Hosts      []string   // Allowlist of the AllowedHosts mode
+	Include    []*Pattern // Patterns of the crawled URLs, any URL if empty
+	Exclude    []*Pattern // Patterns of the URLs never crawled
+	PathPrefix string     // Path prefix of the crawled URLs
+}
+
+// withRoot returns a copy of the scope for the given root URL.
+func (s Scope) withRoot(root *url.URL) Scope {
+	s.Root = root
+	return s
+}
+
+// ContainsHost reports whether the host belongs to the scope.
+func (s Scope) ContainsHost(host string) bool {
+	host = strings.ToLower(host)
+	root := strings.ToLower(s.Root.Hostname())
+	if host == root {
+		return true
+	}
+	switch s.Mode {
+	case RegistrableDomain:
+		if net.ParseIP(root) != nil {
+			return false
+		}
+		domain, err := publicsuffix.EffectiveTLDPlusOne(root)
+		if err != nil {
+			return false
+		}
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	case AllowedHosts:
+		for _, allowed := range s.Hosts {
+			if strings.ToLower(allowed) == host {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Contains reports whether the URL belongs to the scope.
+func (s Scope) Contains(u *url.URL) bool {
+	return s.ContainsHost(u.Hostname())
+}
+
+// IsSubdomain checks if a given link belongs to the scope.
+// If the root URL is exactly the same as the link, it returns false.
+func (s Scope) IsSubdomain(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return s.Contains(u) &&
+		(u.Hostname() != s.Root.Hostname() || u.RequestURI() != s.Root.RequestURI()) // Avoid same url
+}
+
+// Allows reports whether the link is crawled: its host belongs to the scope,
+// its path starts with the path prefix, it matches one of the include
+// patterns if there is any, and none of the exclude patterns.
+// The link is expected in its canonical form.
+func (s Scope) Allows(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || !s.Contains(u) || !hasPathPrefix(u.Path, s.PathPrefix) {
+		return false
+	}
+	if len(s.Include) > 0 && !matchAny(s.Include, link) {
+		return false
+	}
+	return !matchAny(s.Exclude, link)
+}
+
+// hasPathPrefix reports whether the path starts with the prefix at a path
+// segment boundary, so that "/docs" is a prefix of "/docs/intro" but not of
+// "/docs-old".
+func hasPathPrefix(path, prefix string) bool {
+	dir := strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// matchAny reports whether the link matches any of the patterns.
+func matchAny(patterns []*Pattern, link string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(link) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package crawler_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeContainsHost(t *testing.T) {
+	tests := []struct {
+		root     string
+		mode     crawler.HostMode
+		hosts    []string
+		host     string
+		expected bool
+	}{
+		{"https://www.example.com/", crawler.ExactHost, nil, "www.example.com", true},
+		{"https://www.example.com/", crawler.ExactHost, nil, "WWW.Example.com", true},
+		{"https://www.example.com/", crawler.ExactHost, nil, "blog.example.com", false},
+		{"https://www.example.com/", crawler.RegistrableDomain, nil, "blog.example.com", true},
+		{"https://www.example.com/", crawler.RegistrableDomain, nil, "example.com", true},
+		{"https://www.example.com/", crawler.RegistrableDomain, nil, "a.b.example.com", true},
+		{"https://www.example.com/", crawler.RegistrableDomain, nil, "notexample.com", false},
+		{"https://www.example.co.uk/", crawler.RegistrableDomain, nil, "shop.example.co.uk", true},
+		{"https://www.example.co.uk/", crawler.RegistrableDomain, nil, "other.co.uk", false},
+		{"https://a.github.io/", crawler.RegistrableDomain, nil, "b.github.io", false},
+		{"http://localhost:8080/", crawler.RegistrableDomain, nil, "localhost", true},
+		{"http://127.0.0.1:8080/", crawler.RegistrableDomain, nil, "127.0.0.1", true},
+		{"http://127.0.0.1:8080/", crawler.RegistrableDomain, nil, "10.0.0.1", false},
+		{"http://[::1]:8080/", crawler.RegistrableDomain, nil, "::1", true},
+		{"http://[::1]:8080/", crawler.RegistrableDomain, nil, "a::1", false},
+		{"https://www.example.com/", crawler.AllowedHosts, []string{"cdn.other.com"}, "cdn.other.com", true},
+		{"https://www.example.com/", crawler.AllowedHosts, []string{"cdn.other.com"}, "www.example.com", true},
+		{"https://www.example.com/", crawler.AllowedHosts, []string{"cdn.other.com"}, "blog.example.com", false},
+	}
+
+	for _, tt := range tests {
+		root, _ := url.Parse(tt.root)
+		scope := crawler.Scope{Root: root, Mode: tt.mode, Hosts: tt.hosts}
+		assert.Equal(t, tt.expected, scope.ContainsHost(tt.host), "ContainsHost(%q) from %q", tt.host, tt.root)
+	}
+}
+
+func TestScopeIsSubdomain(t *testing.T) {
+	root, _ := url.Parse("https://www.example.com/")
+	scope := crawler.Scope{Root: root, Mode: crawler.RegistrableDomain}
+	assert.True(t, scope.IsSubdomain("https://blog.example.com/"))
+	assert.True(t, scope.IsSubdomain("https://www.example.com/about"))
+	assert.False(t, scope.IsSubdomain("https://www.example.com/"))
+	assert.False(t, scope.IsSubdomain("https://www.other.com/"))
+	assert.False(t, scope.IsSubdomain(" https://www.example.com/"))
+}
+
+func TestParseHostMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected crawler.HostMode
+		fails    bool
+	}{
+		{"exact", crawler.ExactHost, false},
+		{"domain", crawler.RegistrableDomain, false},
+		{"hosts", crawler.AllowedHosts, false},
+		{"something", crawler.ExactHost, true},
+	}
+	for _, tt := range tests {
+		mode, err := crawler.ParseHostMode(tt.name)
+		assert.Equal(t, tt.expected, mode)
+		assert.Equal(t, tt.fails, err != nil)
+	}
+}
+
+func TestRecursiveScope(t *testing.T) {
+	fetcher := NewFileFetcher(t, HtmlFiles)
+
+	// The root links to the tree on another subdomain
+	root := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		if link == "http://parserdigital.com" {
+			return fetcher.Fetch(ctx, "http://www.parserdigital.com")
+		}
+		return fetcher.Fetch(ctx, link)
+	})
+
+	parsedUrl, _ := url.Parse("http://parserdigital.com")
+	tests := []struct {
+		mode     crawler.HostMode
+		expected int
+	}{
+		{crawler.ExactHost, 1},
+		{crawler.RegistrableDomain, 8},
+	}
+	for _, tt := range tests {
+		opts := crawler.Options{Fetcher: root, Scope: crawler.Scope{Mode: tt.mode}}
+		result := crawler.NewRecursive(parsedUrl, opts).Run(context.Background())
+		assert.Equal(t, tt.expected, len(result))
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		expr     string
+		link     string
+		expected bool
+	}{
+		{"http://www.example.com/*", "http://www.example.com/A", true},
+		{"http://www.example.com/*", "http://www.example.com/A/B", false},
+		{"http://www.example.com/**", "http://www.example.com/A/B", true},
+		{"**/blog/**", "http://www.example.com/blog/2023/post", true},
+		{"**/blog/**", "http://www.example.com/news/post", false},
+		{"**.pdf", "http://www.example.com/docs/file.pdf", true},
+		{"**.pdf", "http://www.example.com/docs/file.pdf?x=1", false},
+		{"http://www.example.com/?", "http://www.example.com/A", true},
+		{"http://www.example.com/?", "http://www.example.com/AB", false},
+		{"re:/tag/", "http://www.example.com/tag/go", true},
+		{"re:\\?page=\\d+$", "http://www.example.com/list?page=10", true},
+		{"re:^https://", "http://www.example.com/", false},
+	}
+
+	for _, tt := range tests {
+		pattern, err := crawler.ParsePattern(tt.expr)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expr, pattern.String())
+		assert.Equal(t, tt.expected, pattern.Match(tt.link), "%q matching %q", tt.expr, tt.link)
+	}
+
+	_, err := crawler.ParsePattern("re:[a-")
+	assert.NotNil(t, err)
+}
+
+// Patterns parses the expressions of patterns.
+func Patterns(t *testing.T, exprs ...string) []*crawler.Pattern {
+	patterns := []*crawler.Pattern{}
+	for _, expr := range exprs {
+		pattern, err := crawler.ParsePattern(expr)
+		if err != nil {
+			t.Fatalf("Error parsing pattern: %v", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func TestScopeAllows(t *testing.T) {
+	root, _ := url.Parse("http://www.example.com/docs/")
+	scope := crawler.Scope{
+		Root:       root,
+		Include:    Patterns(t, "**/docs/**", "re:/api/"),
+		Exclude:    Patterns(t, "**.pdf", "re:[?&]print="),
+		PathPrefix: "/docs/",
+	}
+
+	tests := []struct {
+		link     string
+		expected bool
+	}{
+		{"http://www.example.com/docs/intro", true},
+		{"http://www.example.com/docs/api/v1", true},
+		{"http://www.example.com/docs/file.pdf", false},
+		{"http://www.example.com/docs/intro?print=1", false},
+		{"http://www.example.com/blog/api/v1", false},
+		{"http://www.other.com/docs/intro", false},
+		{" http://www.example.com/docs/intro", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, scope.Allows(tt.link), "Allows(%q)", tt.link)
+	}
+
+	// The zero value only checks the host
+	assert.True(t, crawler.Scope{Root: root}.Allows("http://www.example.com/blog/"))
+
+	// The path prefix stops at a path segment boundary
+	for _, prefix := range []string{"/docs", "/docs/"} {
+		scope := crawler.Scope{Root: root, PathPrefix: prefix}
+		assert.True(t, scope.Allows("http://www.example.com/docs"), prefix)
+		assert.True(t, scope.Allows("http://www.example.com/docs/x"), prefix)
+		assert.False(t, scope.Allows("http://www.example.com/docs-old/x"), prefix)
+		assert.False(t, scope.Allows("http://www.example.com/docsx"), prefix)
+	}
+}
+
+func TestRecursiveScopeRules(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	tests := []struct {
+		scope    crawler.Scope
+		expected []string
+	}{
+		{
+			crawler.Scope{Exclude: Patterns(t, "**/B")},
+			[]string{"http://www.parserdigital.com", "http://www.parserdigital.com/A", "http://www.parserdigital.com/C",
+				"http://www.parserdigital.com/D", "http://www.parserdigital.com/F"},
+		},
+		{
+			crawler.Scope{Include: Patterns(t, "re:/[AC]$")},
+			[]string{"http://www.parserdigital.com", "http://www.parserdigital.com/A", "http://www.parserdigital.com/C"},
+		},
+		{
+			crawler.Scope{PathPrefix: "/B"},
+			[]string{"http://www.parserdigital.com", "http://www.parserdigital.com/B"},
+		},
+	}
+
+	for _, tt := range tests {
+		opts := crawler.Options{Fetcher: NewFileFetcher(t, HtmlFiles), Scope: tt.scope}
+		for _, strategy := range []crawler.Strategy{
+			crawler.NewRecursive(parsedUrl, opts),
+			crawler.NewRecursiveParallel(parsedUrl, opts),
+		} {
+			assert.ElementsMatch(t, tt.expected, URLs(strategy.Run(context.Background())))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Candidate is a target waiting to be crawled by the BestFirst strategy, along
+// with the links found to it so far.
+type Candidate struct {
+	Target  PageResult // Target to crawl, with its depth and parent
+	Inbound int        // Number of pages found linking to the target so far
+	Anchors []string   // Anchor texts of the links found to the target so far
+}
+
+// Scorer scores the targets of the BestFirst strategy, which crawls first the
+// targets with the highest score.
+type Scorer interface {
+	Score(candidate Candidate) float64
+}
+
+// ScorerFunc is an adapter to allow the use of ordinary functions as a Scorer.
+type ScorerFunc func(candidate Candidate) float64
+
+// Score calls f(candidate).
+func (f ScorerFunc) Score(candidate Candidate) float64 {
+	return f(candidate)
+}
+
+// DepthScorer scores highest the targets closest to the root URL.
+var DepthScorer Scorer = ScorerFunc(func(candidate Candidate) float64 {
+	return -float64(candidate.Target.Depth)
+})
+
+// InboundScorer scores highest the targets linked from the most pages found so
+// far.
+var InboundScorer Scorer = ScorerFunc(func(candidate Candidate) float64 {
+	return float64(candidate.Inbound)
+})
+
+// NewKeywordScorer creates a Scorer scoring the targets by the number of
+// keywords found, case insensitively, in their URL or in the anchor text of the
+// links to them.
+func NewKeywordScorer(keywords ...string) Scorer {
+	lower := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			lower = append(lower, keyword)
+		}
+	}
+	return ScorerFunc(func(candidate Candidate) float64 {
+		text := strings.ToLower(candidate.Target.URL + " " + strings.Join(candidate.Anchors, " "))
+		score := 0
+		for _, keyword := range lower {
+			if strings.Contains(text, keyword) {
+				score++
+			}
+		}
+		return float64(score)
+	})
+}
+
+// ParseScorer returns the built-in scorer with the given name: "depth",
+// "inbound" or "keyword", which matches the given keywords.
+func ParseScorer(name string, keywords []string) (Scorer, error) {
+	switch name {
+	case "depth":
+		return DepthScorer, nil
+	case "inbound":
+		return InboundScorer, nil
+	case "keyword":
+		if len(keywords) == 0 {
+			return nil, fmt.Errorf("scorer %q without keywords", name)
+		}
+		return NewKeywordScorer(keywords...), nil
+	}
+	return nil, fmt.Errorf("unknown scorer %q", name)
+}
@@ -0,0 +1,61 @@
+package crawler_test
+
+import (
+	"testing"
+
+	"github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScorers(t *testing.T) {
+	shallow := crawler.Candidate{Target: crawler.PageResult{URL: "http://www.example.com/about", Depth: 1}}
+	linked := crawler.Candidate{
+		Target:  crawler.PageResult{URL: "http://www.example.com/p/2", Depth: 3},
+		Inbound: 4,
+		Anchors: []string{"Contact Us", "Team"},
+	}
+
+	tests := []struct {
+		name    string
+		scorer  crawler.Scorer
+		shallow float64
+		linked  float64
+	}{
+		{"depth", crawler.DepthScorer, -1, -3},
+		{"inbound", crawler.InboundScorer, 0, 4},
+		{"keyword url", crawler.NewKeywordScorer("ABOUT"), 1, 0},
+		{"keyword anchors", crawler.NewKeywordScorer("contact", "team", " "), 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.shallow, tt.scorer.Score(shallow))
+			assert.Equal(t, tt.linked, tt.scorer.Score(linked))
+		})
+	}
+}
+
+func TestParseScorer(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords []string
+		fails    bool
+	}{
+		{"depth", nil, false},
+		{"inbound", nil, false},
+		{"keyword", []string{"blog"}, false},
+		{"keyword", nil, true},
+		{"pagerank", nil, true},
+	}
+
+	for _, tt := range tests {
+		scorer, err := crawler.ParseScorer(tt.name, tt.keywords)
+		if tt.fails {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+			assert.NotNil(t, scorer)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// SitemapMode selects how the sitemaps of the root URL seed a crawl.
+type SitemapMode int
+
+const (
+	// NoSitemaps crawls the URLs discovered from the links only.
+	NoSitemaps SitemapMode = iota
+	// SitemapAndLinks crawls the URLs listed in the sitemaps along with the
+	// URLs discovered from the links.
+	SitemapAndLinks
+	// SitemapOnly crawls the root URL and the URLs listed in the sitemaps,
+	// without following any link.
+	SitemapOnly
+)
+
+// sitemapModes maps the names of the sitemap modes, as used by the CLI, to
+// their values.
+var sitemapModes = map[string]SitemapMode{
+	"none":  NoSitemaps,
+	"links": SitemapAndLinks,
+	"only":  SitemapOnly,
+}
+
+// ParseSitemapMode returns the sitemap mode with the given name: "none",
+// "links" or "only".
+func ParseSitemapMode(name string) (SitemapMode, error) {
+	mode, ok := sitemapModes[name]
+	if !ok {
+		return NoSitemaps, fmt.Errorf("unknown sitemap mode %q", name)
+	}
+	return mode, nil
+}
+
+// SitemapEntry is a URL listed in a sitemap.
+type SitemapEntry struct {
+	URL     string // URL of the page
+	Sitemap string // URL of the sitemap listing the page
+}
+
+// sitemapLoc is a <url> or <sitemap> element of a sitemap file.
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// DefaultMaxSitemapSize is the maximum number of bytes read from a sitemap once
+// decompressed when no limit is configured, the maximum size allowed by the
+// sitemap protocol.
+const DefaultMaxSitemapSize = 50 << 20
+
+// Sitemaps discovers and reads the sitemaps of a site.
+type Sitemaps struct {
+	fetcher Fetcher
+	robots  *Robots
+	maxSize int64
+}
+
+// NewSitemaps creates a new Sitemaps fetching the sitemaps with the given
+// fetcher and reading the Sitemap lines of the robots.txt files with the given
+// robots. At most maxSize bytes of every sitemap are read once decompressed,
+// DefaultMaxSitemapSize if it is not positive.
+func NewSitemaps(fetcher Fetcher, robots *Robots, maxSize int64) *Sitemaps {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSitemapSize
+	}
+	return &Sitemaps{fetcher: fetcher, robots: robots, maxSize: maxSize}
+}
+
+// Discover returns the URLs of the sitemaps of the root URL's host: the ones
+// listed in its robots.txt file, or the conventional /sitemap.xml if there are
+// none.
+func (s *Sitemaps) Discover(ctx context.Context, root *url.URL) []string {
+	if sitemaps := s.robots.Sitemaps(ctx, root.String()); len(sitemaps) > 0 {
+		return sitemaps
+	}
+	return []string{root.Scheme + "://" + root.Host + "/sitemap.xml"}
+}
+
+// Entries returns the URLs listed in the sitemaps of the root URL's host,
+// following the sitemap indexes. Each sitemap is read once, and the entries of
+// the sitemaps that cannot be fetched or parsed are skipped from the error on.
+func (s *Sitemaps) Entries(ctx context.Context, root *url.URL) []SitemapEntry {
+	entries := []SitemapEntry{}
+	queue := s.Discover(ctx, root)
+	seen := map[string]bool{}
+	for len(queue) > 0 && ctx.Err() == nil {
+		sitemap := queue[0]
+		queue = queue[1:]
+		if seen[sitemap] {
+			continue
+		}
+		seen[sitemap] = true
+
+		urls, sitemaps, _ := s.Read(ctx, sitemap)
+		for _, link := range urls {
+			entries = append(entries, SitemapEntry{URL: link, Sitemap: sitemap})
+		}
+		queue = append(queue, sitemaps...)
+	}
+	return entries
+}
+
+// Read fetches and parses a sitemap, which may be gzipped. It returns the
+// page URLs of a urlset, or the sitemap URLs of a sitemap index. The sitemap
+// is downloaded even if the fetcher probes the content type of the URLs.
+// The URLs read before an error, such as a sitemap truncated to the maximum
+// size, are returned along with the error.
+func (s *Sitemaps) Read(ctx context.Context, link string) ([]string, []string, error) {
+	resp, err := s.fetcher.Fetch(withoutProbe(ctx), link)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, nil, fmt.Errorf("sitemap %s: status %d", link, resp.StatusCode)
+	}
+	return parseSitemap(resp.Body, s.maxSize)
+}
+
+// parseSitemap parses a sitemap file, decompressing it first if it is gzipped.
+// The <url> and <sitemap> elements are decoded one at a time, so that the URLs
+// read before an error are returned along with it.
+func parseSitemap(body io.Reader, maxSize int64) ([]string, []string, error) {
+	buffered := bufio.NewReader(io.LimitReader(body, maxSize))
+	var reader io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		reader = io.LimitReader(gz, maxSize)
+	}
+
+	decoder := xml.NewDecoder(reader)
+	urls, sitemaps := []string{}, []string{}
+	root := "" // Name of the root element, urlset or sitemapindex
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && root != "" {
+			return urls, sitemaps, nil
+		}
+		if err != nil {
+			return urls, sitemaps, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "urlset" && root != "sitemapindex" {
+				return nil, nil, fmt.Errorf("unknown sitemap element %q", root)
+			}
+			continue
+		}
+		if (root == "urlset" && start.Name.Local == "url") || (root == "sitemapindex" && start.Name.Local == "sitemap") {
+			var element sitemapLoc
+			if err := decoder.DecodeElement(&element, &start); err != nil {
+				return urls, sitemaps, err
+			}
+			loc := strings.TrimSpace(element.Loc)
+			switch {
+			case loc == "":
+			case root == "urlset":
+				urls = append(urls, loc)
+			default:
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package crawler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	crawler "github.com/paconte/gocrawler/crawler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Gzip compresses the content.
+func Gzip(t *testing.T, content string) string {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("Error compressing: %v", err)
+	}
+	writer.Close()
+	return buf.String()
+}
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://www.example.com/A</loc><lastmod>2023-01-01</lastmod></url>
+	<url><loc> http://www.example.com/B </loc></url>
+	<url><loc></loc></url>
+</urlset>`
+
+const sitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>http://www.example.com/pages.xml</loc></sitemap>
+	<sitemap><loc>http://www.example.com/posts.xml.gz</loc></sitemap>
+	<sitemap><loc>http://www.example.com/index.xml</loc></sitemap>
+</sitemapindex>`
+
+const postsUrlset = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://www.example.com/C</loc></url>
+	<url><loc>http://www.example.com/A</loc></url>
+</urlset>`
+
+func TestSitemapsRead(t *testing.T) {
+	fetcher := NewContentFetcher(map[string]string{
+		"http://www.example.com/sitemap.xml":    urlset,
+		"http://www.example.com/sitemap.xml.gz": Gzip(t, urlset),
+		"http://www.example.com/index.xml":      sitemapIndex,
+		"http://www.example.com/invalid.xml":    "<html></html>",
+		"http://www.example.com/broken.xml":     "<urlset><url>",
+	})
+	sitemaps := crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), 0)
+	pages := []string{"http://www.example.com/A", "http://www.example.com/B"}
+
+	for _, link := range []string{"http://www.example.com/sitemap.xml", "http://www.example.com/sitemap.xml.gz"} {
+		urls, indexed, err := sitemaps.Read(context.Background(), link)
+		assert.Nil(t, err)
+		assert.Equal(t, pages, urls)
+		assert.Empty(t, indexed)
+	}
+
+	urls, indexed, err := sitemaps.Read(context.Background(), "http://www.example.com/index.xml")
+	assert.Nil(t, err)
+	assert.Empty(t, urls)
+	assert.Equal(t, 3, len(indexed))
+
+	for _, link := range []string{
+		"http://www.example.com/invalid.xml",
+		"http://www.example.com/broken.xml",
+		"http://www.example.com/missing.xml",
+	} {
+		_, _, err := sitemaps.Read(context.Background(), link)
+		assert.NotNil(t, err, link)
+	}
+}
+
+func TestSitemapsTruncated(t *testing.T) {
+	// The sitemap is cut in the middle of its third <url> element
+	full := `<urlset><url><loc>http://www.example.com/A</loc></url><url><loc>http://www.example.com/B</loc></url>` +
+		`<url><loc>http://www.example.com/C</loc></url></urlset>`
+	size := strings.Index(full, "/C<")
+	fetcher := NewContentFetcher(map[string]string{
+		"http://www.example.com/robots.txt":   "Sitemap: http://www.example.com/pages.xml",
+		"http://www.example.com/pages.xml":    full,
+		"http://www.example.com/pages.xml.gz": Gzip(t, full),
+	})
+	sitemaps := crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), int64(size))
+	pages := []string{"http://www.example.com/A", "http://www.example.com/B"}
+
+	for _, link := range []string{"http://www.example.com/pages.xml", "http://www.example.com/pages.xml.gz"} {
+		urls, _, err := sitemaps.Read(context.Background(), link)
+		assert.NotNil(t, err, link)
+		assert.Equal(t, pages, urls, link)
+	}
+
+	root, _ := url.Parse("http://www.example.com/")
+	assert.Equal(t, []crawler.SitemapEntry{
+		{URL: "http://www.example.com/A", Sitemap: "http://www.example.com/pages.xml"},
+		{URL: "http://www.example.com/B", Sitemap: "http://www.example.com/pages.xml"},
+	}, sitemaps.Entries(context.Background(), root))
+
+	// The default limit is the maximum size of the sitemap protocol
+	sitemaps = crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), 0)
+	urls, _, err := sitemaps.Read(context.Background(), "http://www.example.com/pages.xml")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(urls))
+	assert.Equal(t, 50*1024*1024, crawler.DefaultMaxSitemapSize)
+}
+
+func TestSitemapsDiscover(t *testing.T) {
+	root, _ := url.Parse("http://www.example.com/start")
+
+	// The sitemaps of the robots.txt file
+	fetcher := NewContentFetcher(map[string]string{
+		"http://www.example.com/robots.txt": "Sitemap: http://www.example.com/index.xml\nUser-agent: *\nDisallow:\nsitemap: http://www.example.com/other.xml",
+	})
+	sitemaps := crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), 0)
+	assert.Equal(t, []string{"http://www.example.com/index.xml", "http://www.example.com/other.xml"}, sitemaps.Discover(context.Background(), root))
+
+	// The conventional sitemap
+	fetcher = NewContentFetcher(map[string]string{})
+	sitemaps = crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), 0)
+	assert.Equal(t, []string{"http://www.example.com/sitemap.xml"}, sitemaps.Discover(context.Background(), root))
+}
+
+func TestSitemapsEntries(t *testing.T) {
+	fetcher := NewContentFetcher(map[string]string{
+		"http://www.example.com/robots.txt":   "Sitemap: http://www.example.com/index.xml",
+		"http://www.example.com/index.xml":    sitemapIndex,
+		"http://www.example.com/pages.xml":    urlset,
+		"http://www.example.com/posts.xml.gz": Gzip(t, postsUrlset),
+	})
+	root, _ := url.Parse("http://www.example.com/")
+	sitemaps := crawler.NewSitemaps(fetcher, crawler.NewRobots(fetcher, crawler.DefaultUserAgent), 0)
+
+	expected := []crawler.SitemapEntry{
+		{URL: "http://www.example.com/A", Sitemap: "http://www.example.com/pages.xml"},
+		{URL: "http://www.example.com/B", Sitemap: "http://www.example.com/pages.xml"},
+		{URL: "http://www.example.com/C", Sitemap: "http://www.example.com/posts.xml.gz"},
+		{URL: "http://www.example.com/A", Sitemap: "http://www.example.com/posts.xml.gz"},
+	}
+	assert.Equal(t, expected, sitemaps.Entries(context.Background(), root))
+}
+
+func TestParseSitemapMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected crawler.SitemapMode
+		fails    bool
+	}{
+		{"none", crawler.NoSitemaps, false},
+		{"links", crawler.SitemapAndLinks, false},
+		{"only", crawler.SitemapOnly, false},
+		{"something", crawler.NoSitemaps, true},
+	}
+	for _, tt := range tests {
+		mode, err := crawler.ParseSitemapMode(tt.name)
+		assert.Equal(t, tt.expected, mode)
+		assert.Equal(t, tt.fails, err != nil)
+	}
+}
+
+func TestRecursiveSitemaps(t *testing.T) {
+	fetcher := NewContentFetcher(map[string]string{
+		"http://www.example.com":             `<html><a href="/A">A</a></html>`,
+		"http://www.example.com/robots.txt":  "User-agent: *\nDisallow: /private",
+		"http://www.example.com/sitemap.xml": `<urlset><url><loc>http://www.example.com/</loc></url><url><loc>http://www.example.com/B</loc></url><url><loc>http://www.example.com/private</loc></url><url><loc>http://www.other.com/C</loc></url></urlset>`,
+		"http://www.example.com/A":           `<html></html>`,
+		"http://www.example.com/B":           `<html><a href="/D">D</a></html>`,
+		"http://www.example.com/D":           `<html></html>`,
+	})
+	root, _ := url.Parse("http://www.example.com")
+	robots := crawler.NewRobots(fetcher, crawler.DefaultUserAgent)
+
+	tests := []struct {
+		mode     crawler.SitemapMode
+		expected []string
+	}{
+		{crawler.NoSitemaps, []string{"http://www.example.com", "http://www.example.com/A"}},
+		{crawler.SitemapOnly, []string{"http://www.example.com", "http://www.example.com/B"}},
+		{crawler.SitemapAndLinks, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/B", "http://www.example.com/D"}},
+	}
+	for _, tt := range tests {
+		opts := crawler.Options{Fetcher: fetcher, Robots: robots, Sitemaps: tt.mode, Limits: crawler.Limits{Milliseconds: 100 * 1000, Requests: 100}}
+		for _, strategy := range []crawler.Strategy{
+			crawler.NewRecursive(root, opts),
+			crawler.NewRecursiveWithLimits(root, opts),
+			crawler.NewRecursiveParallel(root, opts),
+			crawler.NewRecursiveParallelWithLimits(root, opts),
+		} {
+			results := strategy.Run(context.Background())
+			assert.ElementsMatch(t, tt.expected, URLs(results))
+			for _, result := range results {
+				if result.URL == "http://www.example.com/B" {
+					assert.Equal(t, 1, result.Depth)
+					assert.Equal(t, "http://www.example.com/sitemap.xml", result.Parent)
+				}
+			}
+		}
+	}
+
+	// OneLevel collects the sitemap URLs without fetching them
+	tests = []struct {
+		mode     crawler.SitemapMode
+		expected []string
+	}{
+		{crawler.NoSitemaps, []string{"http://www.example.com", "http://www.example.com/A"}},
+		{crawler.SitemapOnly, []string{"http://www.example.com", "http://www.example.com/B"}},
+		{crawler.SitemapAndLinks, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/B"}},
+	}
+	for _, tt := range tests {
+		opts := crawler.Options{Fetcher: fetcher, Robots: robots, Sitemaps: tt.mode}
+		results := crawler.NewOneLevel(root, opts).Run(context.Background())
+		assert.Equal(t, tt.expected, URLs(results))
+	}
+}
+
+func TestSitemapsProbe(t *testing.T) {
+	// The sitemap and the robots.txt file are not HTML
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "Sitemap: http://%s/map.xml\n", r.Host)
+		case "/map.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, "<urlset><url><loc>http://%s/page</loc></url></urlset>", r.Host)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := crawler.NewHTTPFetcher(server.Client())
+	fetcher.Probe = true
+	root, _ := url.Parse(server.URL)
+	opts := crawler.Options{Fetcher: crawler.NewRetryFetcher(fetcher, crawler.RetryPolicy{}), Sitemaps: crawler.SitemapOnly}
+	results := crawler.NewRecursive(root, opts).Run(context.Background())
+	assert.ElementsMatch(t, []string{server.URL, server.URL + "/page"}, URLs(results))
+}
@@ -3,268 +3,292 @@ package crawler
 import (
 	"context"
 	"net/url"
-	"sync"
-	"time"
 )
 
 // Limits represents the limits of a strategy.
-// It contains the maximum number of seconds and requests.
+// It contains the maximum number of seconds and requests of the strategies with
+// limits, the maximum depth of every recursive strategy and the maximum pages
+// per branch of the DepthFirst strategies.
 type Limits struct {
 	Milliseconds int
 	Requests     int
+	MaxDepth     int // Maximum distance in links from the root URL, 0 for no limit
+	BranchPages  int // Maximum pages crawled per branch by the DepthFirst strategies, 0 for no limit
 }
 
-/*
- * ######### RECURSIVE ###########
- */
-
-// This strategy implements asearch approach to crawl URLs recursively,
-// discovering new URLs at each level and continuing the crawling process until
-// there are no more unvisited URLs.
-type Recursive struct {
-	visited map[string]bool // Visited URLs
-	found   map[string]bool // Found URLs
-	url     *url.URL        // Root URL
+// Options represents the configuration shared by all strategies.
+type Options struct {
+	Fetcher        Fetcher       // Fetcher used to download the pages, HTTPFetcher by default
+	Limits         Limits        // Limits of the strategies with limits
+	Workers        int           // Concurrency level of the parallel strategies, DefaultWorkers by default
+	Robots         *Robots       // Robots rules to honor, nil to ignore robots.txt
+	MaxBodySize    int64         // Maximum bytes read from a response body, DefaultMaxBodySize by default
+	Scope          Scope         // URLs that belong to the crawl, the host of the root URL by default
+	Canonicalizer  Canonicalizer // Canonical form of the extracted URLs
+	LinkExtractor  LinkExtractor // Links crawled on every page, the DefaultLinkKinds by default
+	RecordNoFollow bool          // Record the targets of the nofollow links without fetching them
+	Sitemaps       SitemapMode   // Whether the URLs listed in the sitemaps are crawled, NoSitemaps by default
+	Scorer         Scorer        // Ordering of the BestFirst strategies, InboundScorer by default
 }
 
-// NewRecursive creates a new instance of the Recursive strategy.
-func NewRecursive(url *url.URL) *Recursive {
-	strategy := &Recursive{
-		url:     url,
-		found:   map[string]bool{url.String(): true},
-		visited: map[string]bool{},
+// withDefaults returns a copy of the options with the unset fields set to
+// their default values.
+func (o Options) withDefaults() Options {
+	if o.Fetcher == nil {
+		o.Fetcher = NewHTTPFetcher(nil)
 	}
-	return strategy
-}
-
-// Run starts the web crawling process using the Recursive strategy.
-// It returns a list of visited URLs.
-func (s *Recursive) Run() []string {
-	for len(s.visited) != len(s.found) {
-		for link := range s.found {
-			if s.visited[link] {
-				continue
-			}
-			newFounds := CollectMap(Extract(Parse(Download(link)), s.url))
-			for newFound := range newFounds {
-				s.found[newFound] = true
-			}
-			s.visited[link] = true
-		}
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+	if o.MaxBodySize <= 0 {
+		o.MaxBodySize = DefaultMaxBodySize
+	}
+	if o.LinkExtractor == nil {
+		o.LinkExtractor, _ = NewHTMLLinkExtractor()
 	}
-	return MapToList(s.visited)
+	if o.Scorer == nil {
+		o.Scorer = InboundScorer
+	}
+	return o
 }
 
-/*
- * ######### RECURSIVE WITH LIMITS ###########
- */
-
-// RecursiveWithLimits implements the same strategy as the Recursive strategy,
-// but adding limits to the number of requests and time.
-type RecursiveWithLimits struct {
-	visited map[string]bool // Visited URLs
-	found   map[string]bool // Found URLs
-	url     *url.URL        // Root URL
-	limits  Limits
+// allowed reports whether the robots rules allow crawling the link.
+func (o Options) allowed(ctx context.Context, link string) bool {
+	return o.Robots == nil || o.Robots.Allowed(ctx, link)
 }
 
-// NewRecursiveWithLimits creates a new instance of the Recursive strategy.
-func NewRecursiveWithLimits(url *url.URL, limits Limits) *RecursiveWithLimits {
-	strategy := &RecursiveWithLimits{
-		visited: map[string]bool{},
-		found:   map[string]bool{url.String(): true},
-		url:     url,
-		limits:  limits,
+// visit crawls the target and returns its result along with the targets found
+// on it that the scope and the robots rules allow to crawl, so that disallowed
+// URLs are never downloaded, not even as redirect hops. The targets of the
+// nofollow links are only returned, flagged with NoFollow, if they are
+// recorded, and no targets are returned when only the sitemaps are crawled.
+// The maximum depth is applied by the caller, see withinDepth.
+// It returns false if the context was cancelled during the visit.
+func (o Options) visit(ctx context.Context, target PageResult, root *url.URL) (PageResult, []PageResult, bool) {
+	downloaded := Download(withRedirectCheck(ctx, o.redirectCheck(ctx, root)), o.Fetcher, target.URL)
+	extracted := Extract(ctx, Parse(ctx, downloaded, o.MaxBodySize), o.Scope.withRoot(root), o.LinkExtractor)
+	pages := CollectPages(Normalize(ctx, extracted, o.Canonicalizer, root))
+	if len(pages) == 0 || ctx.Err() != nil {
+		return target, nil, false
+	}
+	result := pages[0].Result
+	result.Depth, result.Parent = target.Depth, target.Parent
+	if o.Sitemaps == SitemapOnly {
+		return result, []PageResult{}, true
 	}
-	return strategy
-}
 
-// Run starts the web crawling process using the Recursive strategy with limits.
-// It returns a list of visited URLs.
-func (s *RecursiveWithLimits) Run() []string {
-	isTimeout := false
-	ctx, cancel := context.WithTimeout(
-		context.Background(), time.Duration(s.limits.Milliseconds)*time.Millisecond)
-	defer cancel()
-
-	for len(s.visited) != len(s.found) {
-		// Stop if the number of requests exceeds the limit
-		if len(s.visited) >= s.limits.Requests {
-			break
+	scope := o.Scope.withRoot(root)
+	children := []PageResult{}
+	for link := range pages[0].Links {
+		if scope.Allows(link) && o.allowed(ctx, link) {
+			children = append(children, PageResult{URL: link, Depth: target.Depth + 1, Parent: target.URL})
 		}
-		// Stop if the timeout is exceeded
-		select {
-		case <-ctx.Done():
-			isTimeout = true
-		default:
-			for link := range s.found {
-				if s.visited[link] {
-					continue
-				}
-				newFounds := CollectMap(Extract(Parse(Download(link)), s.url))
-				for newFound := range newFounds {
-					s.found[newFound] = true
-				}
-				s.visited[link] = true
-			}
-		}
-		if isTimeout {
-			break
+	}
+	for link := range pages[0].NoFollow {
+		if o.RecordNoFollow && scope.Allows(link) && o.allowed(ctx, link) {
+			children = append(children, PageResult{URL: link, Depth: target.Depth + 1, Parent: target.URL, NoFollow: true})
 		}
 	}
-	return MapToList(s.visited)
+	return result, children, true
 }
 
-/*
- * ######### RECURSIVE PARALLEL ###########
- */
+// redirectCheck returns the check of the redirect hops of the visits, which
+// stops at the first hop outside the scope or disallowed by the robots rules.
+func (o Options) redirectCheck(ctx context.Context, root *url.URL) func(*url.URL) error {
+	scope := o.Scope.withRoot(root)
+	return func(hop *url.URL) error {
+		if !scope.Contains(hop) {
+			return ErrRedirectOutOfScope
+		}
+		if !o.allowed(ctx, hop.String()) {
+			return ErrRedirectDisallowed
+		}
+		return nil
+	}
+}
 
-// RecursiveParallel implements a parallelized version of the Recursive strategy.
-type RecursiveParallel struct {
-	visited map[string]bool // Visited URLs
-	found   map[string]bool // Found URLs
-	url     *url.URL        // Root URL
-	mutex   sync.Mutex
+// withinDepth returns the targets that are not beyond the maximum depth.
+func (o Options) withinDepth(targets []PageResult) []PageResult {
+	if o.Limits.MaxDepth <= 0 {
+		return targets
+	}
+	within := []PageResult{}
+	for _, target := range targets {
+		if target.Depth <= o.Limits.MaxDepth {
+			within = append(within, target)
+		}
+	}
+	return within
 }
 
-// NewRecursiveParallel creates a new instance of the RecursiveParallel strategy.
-func NewRecursiveParallel(url *url.URL) *RecursiveParallel {
-	strategy := &RecursiveParallel{
-		visited: map[string]bool{},
-		found:   map[string]bool{url.String(): true},
-		url:     url,
-		mutex:   sync.Mutex{},
+// followTargets returns the targets to follow, and records the other ones in
+// noFollow unless they were already recorded.
+func followTargets(targets []PageResult, noFollow map[string]PageResult) []PageResult {
+	follow := []PageResult{}
+	for _, target := range targets {
+		if !target.NoFollow {
+			follow = append(follow, target)
+		} else if _, ok := noFollow[target.URL]; !ok {
+			noFollow[target.URL] = target
+		}
 	}
-	return strategy
+	return follow
 }
 
-// Run starts the web crawling process using the RecursiveParallel strategy.
-// It takes the root URL as input and returns a list of visited URLs.
-func (s *RecursiveParallel) Run() []string {
-	var wg sync.WaitGroup
-	for len(s.visited) != len(s.found) {
-		for link := range s.found {
-			if s.isVisited(link) {
-				continue
-			}
-			wg.Add(1)
-			go s.job(link, s.url, &wg)
+// noFollowResults returns the recorded nofollow targets that were not followed
+// from another link.
+func noFollowResults(noFollow map[string]PageResult, followed func(link string) bool) []PageResult {
+	results := []PageResult{}
+	for link, target := range noFollow {
+		if !followed(link) {
+			results = append(results, target)
 		}
-		wg.Wait()
 	}
-	return MapToList(s.visited)
+	return results
 }
 
-// isVisited checks if a URL has already been visited.
-func (s *RecursiveParallel) isVisited(link string) bool {
-	visited := false
-	s.mutex.Lock()
-	if s.visited[link] {
-		visited = true
+// sitemapTargets returns the targets of the URLs listed in the sitemaps of the
+// root URL that the scope and the robots rules allow to crawl, or none if the
+// sitemaps are not used. The robots.txt files are read for their Sitemap lines
+// even when their rules are ignored.
+func (o Options) sitemapTargets(ctx context.Context, root *url.URL) []PageResult {
+	targets := []PageResult{}
+	if o.Sitemaps == NoSitemaps {
+		return targets
+	}
+	robots := o.Robots
+	if robots == nil {
+		robots = NewRobots(o.Fetcher, DefaultUserAgent)
+	}
+	scope := o.Scope.withRoot(root)
+	rootLink, _ := o.Canonicalizer.Canonicalize(root.String())
+	seen := map[string]bool{rootLink: true}
+	for _, entry := range NewSitemaps(o.Fetcher, robots, 0).Entries(ctx, root) {
+		link, err := o.Canonicalizer.Canonicalize(entry.URL)
+		if err != nil || seen[link] || !scope.Allows(link) || !o.allowed(ctx, link) {
+			continue
+		}
+		seen[link] = true
+		targets = append(targets, PageResult{URL: link, Depth: 1, Parent: entry.Sitemap})
 	}
-	s.mutex.Unlock()
-	return visited
+	return targets
 }
 
-// job performs the crawling job for a specific URL.
-func (s *RecursiveParallel) job(link string, rootUrl *url.URL, wg *sync.WaitGroup) {
-	newFounds := CollectMap(Extract(Parse(Download(link)), rootUrl))
+// rootTarget returns the target of the root URL of a crawl.
+func rootTarget(url *url.URL) PageResult {
+	return PageResult{URL: url.String()}
+}
 
-	s.mutex.Lock()
-	for newFound := range newFounds {
-		s.found[newFound] = true
-	}
-	s.visited[link] = true
-	s.mutex.Unlock()
+/*
+ * ######### RECURSIVE ###########
+ */
 
-	wg.Done()
+// NewRecursive creates a new instance of the Recursive strategy, which crawls
+// the URLs recursively one at a time, breadth first, until there are no more
+// unvisited URLs.
+func NewRecursive(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, EngineConfig{Workers: 1})
+}
+
+/*
+ * ######### RECURSIVE WITH LIMITS ###########
+ */
+
+// NewRecursiveWithLimits creates a new instance of the Recursive strategy
+// limited to the Milliseconds and Requests of the options.
+func NewRecursiveWithLimits(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, EngineConfig{Workers: 1, Budget: true})
+}
+
+/*
+ * ######### RECURSIVE PARALLEL ###########
+ */
+
+// NewRecursiveParallel creates a new instance of the RecursiveParallel
+// strategy, which crawls the URLs breadth first with the Workers of the
+// options.
+func NewRecursiveParallel(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, EngineConfig{})
 }
 
 /*
  * ######### RECURSIVE PARALLEL WITH LIMITS ###########
  */
 
-// RecursiveParallelWithLimits implements a parallelized version of the Recursive strategy
-// It also has a limit of http requests and time.
-type RecursiveParallelWithLimits struct {
-	visited map[string]bool // Visited URLs
-	found   map[string]bool // Found URLs
-	url     *url.URL        // Root URL
-	mutex   sync.Mutex
-	limits  Limits
+// NewRecursiveParallelWithLimits creates a new instance of the
+// RecursiveParallel strategy limited to the Milliseconds and Requests of the
+// options.
+func NewRecursiveParallelWithLimits(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, EngineConfig{Budget: true})
 }
 
-// RecursiveParallelWithLimits creates a new instance of the RecursiveParallelWithLimits strategy.
-func NewRecursiveParallelWithLimits(url *url.URL, limits Limits) *RecursiveParallelWithLimits {
-	strategy := &RecursiveParallelWithLimits{
-		visited: map[string]bool{},
-		found:   map[string]bool{url.String(): true},
-		url:     url,
-		mutex:   sync.Mutex{},
-		limits:  limits,
-	}
-	return strategy
+/*
+ * ######### BEST FIRST ###########
+ */
+
+// NewBestFirst creates a new instance of the BestFirst strategy, which crawls
+// first the URLs with the highest score given by the Scorer of the options.
+// The URLs are crawled with the Workers of the options, the order is only
+// exact with a single worker.
+func NewBestFirst(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, bestFirstConfig(opts, false))
 }
 
-// Run starts the web crawling process using the RecursiveParallelWithLimits strategy.
-// It takes the root URL as input and returns a list of visited URLs.
-func (s *RecursiveParallelWithLimits) Run() []string {
-	var wg sync.WaitGroup
-	isTimeout := false
+/*
+ * ######### BEST FIRST WITH LIMITS ###########
+ */
 
-	ctx, cancel := context.WithTimeout(
-		context.Background(), time.Duration(s.limits.Milliseconds)*time.Millisecond)
-	defer cancel()
+// NewBestFirstWithLimits creates a new instance of the BestFirst strategy
+// limited to the Milliseconds and Requests of the options, so that the
+// requests are spent on the URLs with the highest scores.
+func NewBestFirstWithLimits(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, bestFirstConfig(opts, true))
+}
 
-	for len(s.visited) != len(s.found) {
-		// Stop if the number of requests exceeds the limit
-		if len(s.visited) >= s.limits.Requests {
-			break
-		}
-		// Stop if the timeout is exceeded
-		select {
-		case <-ctx.Done():
-			isTimeout = true
-		default:
-			for link := range s.found {
-				if s.isVisited(link) {
-					continue
-				}
-				wg.Add(1)
-				go s.job(link, s.url, &wg)
-			}
-			wg.Wait()
-		}
-		if isTimeout {
-			break
-		}
+// bestFirstConfig returns the configuration of the engine of the BestFirst
+// strategies.
+func bestFirstConfig(opts Options, budget bool) EngineConfig {
+	scorer := opts.Scorer
+	if scorer == nil {
+		scorer = InboundScorer
+	}
+	return EngineConfig{
+		Frontier: func() Frontier { return NewScoredFrontier(scorer) },
+		Budget:   budget,
 	}
-	return MapToList(s.visited)
 }
 
-// isVisited checks if a URL has already been visited.
-func (s *RecursiveParallelWithLimits) isVisited(link string) bool {
-	visited := false
-	s.mutex.Lock()
-	if s.visited[link] {
-		visited = true
-	}
-	s.mutex.Unlock()
-	return visited
+/*
+ * ######### DEPTH FIRST ###########
+ */
+
+// NewDepthFirst creates a new instance of the DepthFirst strategy, which
+// follows each branch of the site to the bottom before backtracking, crawling
+// at most the BranchPages of the options limits of every branch.
+// The URLs are crawled one at a time, since concurrent workers would visit the
+// siblings of a page before its children.
+func NewDepthFirst(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, depthFirstConfig(opts, false))
 }
 
-// job performs the crawling job for a specific URL.
-func (s *RecursiveParallelWithLimits) job(link string, rootUrl *url.URL, wg *sync.WaitGroup) {
-	newFounds := CollectMap(Extract(Parse(Download(link)), rootUrl))
+/*
+ * ######### DEPTH FIRST WITH LIMITS ###########
+ */
 
-	s.mutex.Lock()
-	for newFound := range newFounds {
-		s.found[newFound] = true
-	}
-	s.visited[link] = true
-	s.mutex.Unlock()
+// NewDepthFirstWithLimits creates a new instance of the DepthFirst strategy
+// limited to the Milliseconds and Requests of the options.
+func NewDepthFirstWithLimits(url *url.URL, opts Options) *Engine {
+	return NewEngine(url, opts, depthFirstConfig(opts, true))
+}
 
-	wg.Done()
+// depthFirstConfig returns the configuration of the engine of the DepthFirst
+// strategies.
+func depthFirstConfig(opts Options, budget bool) EngineConfig {
+	frontier := NewLIFOFrontier
+	if max := opts.Limits.BranchPages; max > 0 {
+		frontier = func() Frontier { return NewBranchFrontier(NewLIFOFrontier(), max) }
+	}
+	return EngineConfig{Frontier: frontier, Workers: 1, Budget: budget}
 }
 
 /*
@@ -272,19 +296,48 @@ func (s *RecursiveParallelWithLimits) job(link string, rootUrl *url.URL, wg *syn
  */
 
 // This strategy crawls the root URL and collects URLs up to one level deep.
-// It returns a list of collected URLs.
+// It returns the result of the root URL, with its status and error, and the
+// results of the collected URLs, which are found but not fetched. The URLs
+// listed in the sitemaps are collected as well in the sitemap modes.
 type OneLevel struct {
-	url *url.URL
+	url  *url.URL
+	opts Options
 }
 
 // NewOneLevel creates a new instance of the OneLevel strategy.
-func NewOneLevel(url *url.URL) *OneLevel {
-	strategy := &OneLevel{url: url}
+func NewOneLevel(url *url.URL, opts Options) *OneLevel {
+	strategy := &OneLevel{url: url, opts: opts.withDefaults()}
 	return strategy
 }
 
 // Run starts the web crawling process using the OneLevel strategy.
-// It takes the root URL as input and returns a list of collected URLs.
-func (s *OneLevel) Run() []string {
-	return MapToList(CollectMap(Extract(Parse(Download(s.url.String())), s.url)))
+// It takes the root URL as input and returns the result of the root URL along
+// with the results of the collected URLs.
+func (s *OneLevel) Run(ctx context.Context) []PageResult {
+	results := []PageResult{}
+	s.Stream(ctx, func(result PageResult) {
+		results = append(results, result)
+	})
+	return results
+}
+
+// Stream starts the web crawling process like Run, but emits the result of the
+// root URL and then the results of the collected URLs one by one.
+func (s *OneLevel) Stream(ctx context.Context, emit func(PageResult)) {
+	if !s.opts.allowed(ctx, s.url.String()) {
+		return
+	}
+	result, children, ok := s.opts.visit(ctx, rootTarget(s.url), s.url)
+	if !ok {
+		return
+	}
+	emit(result)
+	rootLink, _ := s.opts.Canonicalizer.Canonicalize(s.url.String())
+	seen := map[string]bool{rootLink: true}
+	for _, child := range append(children, s.opts.sitemapTargets(ctx, s.url)...) {
+		if !seen[child.URL] {
+			seen[child.URL] = true
+			emit(child)
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package crawler_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
@@ -26,7 +27,7 @@ func BenchmarkOneLevel(b *testing.B) {
 
 	// Test the function
 	for i := 0; i < b.N; i++ {
-		crawler.Run(url, "OneLevel", emptyLimits)
+		crawler.Run(context.Background(), url, "OneLevel", emptyOptions)
 	}
 }
 
@@ -43,7 +44,7 @@ func BenchmarkRecursive(b *testing.B) {
 
 	// Test the function
 	for i := 0; i < b.N; i++ {
-		crawler.Run("http://www.parserdigital.com", "Recursive", emptyLimits)
+		crawler.Run(context.Background(), "http://www.parserdigital.com", "Recursive", emptyOptions)
 	}
 }
 
@@ -60,6 +61,6 @@ func BenchmarkRecursiveParallel(b *testing.B) {
 
 	// Test the function
 	for i := 0; i < b.N; i++ {
-		crawler.Run("http://www.parserdigital.com", "RecursiveParallel", emptyLimits)
+		crawler.Run(context.Background(), "http://www.parserdigital.com", "RecursiveParallel", emptyOptions)
 	}
 }
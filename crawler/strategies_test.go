@@ -1,8 +1,12 @@
 package crawler_test
 
 import (
+	"context"
+	"net/http"
 	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/paconte/gocrawler/crawler"
 
@@ -20,6 +24,15 @@ var HtmlFiles = map[string]string{
 	"http://www.parserdigital.com/F": "testdata/treeLevel3F.html",
 }
 
+// URLs returns the URLs of the results.
+func URLs(results []crawler.PageResult) []string {
+	urls := make([]string, 0, len(results))
+	for _, result := range results {
+		urls = append(urls, result.URL)
+	}
+	return urls
+}
+
 func TestOneLevel(t *testing.T) {
 	// Activate httpmock
 	httpmock.Activate()
@@ -36,10 +49,10 @@ func TestOneLevel(t *testing.T) {
 		content  string
 		expected int
 	}{
-		{"https://parserdigital.com/", 200, fileContent, 23},
-		{"https://google.com", 200, "", 0},
-		{"A", 400, "", 0},
-		{"B", 200, "", 0},
+		{"https://parserdigital.com/", 200, fileContent, 25},
+		{"https://google.com", 200, "", 1},
+		{"A", 400, "", 1},
+		{"B", 200, "", 1},
 	}
 
 	for _, tt := range tests {
@@ -50,10 +63,17 @@ func TestOneLevel(t *testing.T) {
 
 		// Test the function
 		parsedUrl, _ := url.Parse(tt.url)
-		strategy := crawler.NewOneLevel(parsedUrl)
-		result := strategy.Run()
+		strategy := crawler.NewOneLevel(parsedUrl, crawler.Options{})
+		result := strategy.Run(context.Background())
 		assert.Equal(t, tt.expected, len(result))
+		assert.Equal(t, tt.status, result[0].Status)
 	}
+
+	// The failure of the root URL is reported
+	parsedUrl, _ := url.Parse("https://unreachable.com/")
+	result := crawler.NewOneLevel(parsedUrl, crawler.Options{}).Run(context.Background())
+	assert.Equal(t, []string{"https://unreachable.com/"}, URLs(result))
+	assert.NotNil(t, result[0].Err)
 }
 
 func TestRecursive(t *testing.T) {
@@ -73,14 +93,32 @@ func TestRecursive(t *testing.T) {
 
 	// Test the function
 	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
-	strategy := crawler.NewRecursive(parsedUrl)
-	result := strategy.Run()
+	strategy := crawler.NewRecursive(parsedUrl, crawler.Options{})
+	result := strategy.Run(context.Background())
 	info := httpmock.GetCallCountInfo()
 
 	assert.Equal(t, 7, len(result))
 	for link := range HtmlFiles {
 		assert.Equal(t, 1, info["GET "+link])
 	}
+	AssertResults(t, result)
+}
+
+// AssertResults checks the results of a crawl of the HtmlFiles tree.
+func AssertResults(t *testing.T, results []crawler.PageResult) {
+	for _, result := range results {
+		assert.Nil(t, result.Err)
+		assert.Equal(t, 200, result.Status)
+		assert.Equal(t, result.URL, result.FinalURL)
+		assert.Greater(t, result.Size, int64(0))
+		if result.URL == "http://www.parserdigital.com" {
+			assert.Equal(t, 0, result.Depth)
+			assert.Equal(t, "", result.Parent)
+		} else {
+			assert.Greater(t, result.Depth, 0)
+			assert.Contains(t, HtmlFiles, result.Parent)
+		}
+	}
 }
 
 func TestRecursiveParallel(t *testing.T) {
@@ -100,14 +138,15 @@ func TestRecursiveParallel(t *testing.T) {
 
 	// Test the function
 	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
-	strategy := crawler.NewRecursiveParallel(parsedUrl)
-	result := strategy.Run()
+	strategy := crawler.NewRecursiveParallel(parsedUrl, crawler.Options{})
+	result := strategy.Run(context.Background())
 	info := httpmock.GetCallCountInfo()
 
 	assert.Equal(t, 7, len(result))
 	for link := range HtmlFiles {
 		assert.Equal(t, 1, info["GET "+link])
 	}
+	AssertResults(t, result)
 }
 
 func TestRecursiveWithLimits(t *testing.T) {
@@ -140,8 +179,8 @@ func TestRecursiveWithLimits(t *testing.T) {
 	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
 	for _, tt := range tests {
 		strategy := crawler.NewRecursiveWithLimits(
-			parsedUrl, crawler.Limits{Milliseconds: tt.milliseconds, Requests: tt.requests})
-		result := strategy.Run()
+			parsedUrl, crawler.Options{Limits: crawler.Limits{Milliseconds: tt.milliseconds, Requests: tt.requests}})
+		result := strategy.Run(context.Background())
 		info := httpmock.GetCallCountInfo()
 
 		assert.Equal(t, tt.expected, len(result))
@@ -183,8 +222,8 @@ func TestRecursiveParallelWithLimits(t *testing.T) {
 	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
 	for _, tt := range tests {
 		strategy := crawler.NewRecursiveParallelWithLimits(
-			parsedUrl, crawler.Limits{Milliseconds: tt.milliseconds, Requests: tt.requests})
-		result := strategy.Run()
+			parsedUrl, crawler.Options{Limits: crawler.Limits{Milliseconds: tt.milliseconds, Requests: tt.requests}})
+		result := strategy.Run(context.Background())
 		info := httpmock.GetCallCountInfo()
 
 		assert.Equal(t, tt.expected, len(result))
@@ -195,3 +234,252 @@ func TestRecursiveParallelWithLimits(t *testing.T) {
 		}
 	}
 }
+
+func TestRecursiveCancel(t *testing.T) {
+	files := NewFileFetcher(t, HtmlFiles)
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	limits := crawler.Limits{Milliseconds: 100 * 1000, Requests: 100}
+
+	for _, newStrategy := range []func(crawler.Fetcher) crawler.Strategy{
+		func(f crawler.Fetcher) crawler.Strategy {
+			return crawler.NewRecursive(parsedUrl, crawler.Options{Fetcher: f})
+		},
+		func(f crawler.Fetcher) crawler.Strategy {
+			return crawler.NewRecursiveWithLimits(parsedUrl, crawler.Options{Fetcher: f, Limits: limits})
+		},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Cancel the crawl while the second page is being fetched
+		fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+			if link != "http://www.parserdigital.com" {
+				cancel()
+			}
+			return files.Fetch(ctx, link)
+		})
+		result := newStrategy(fetcher).Run(ctx)
+		assert.Equal(t, []string{"http://www.parserdigital.com"}, URLs(result))
+		cancel()
+	}
+}
+
+func TestRecursiveParallelCancelled(t *testing.T) {
+	fetcher := NewFileFetcher(t, HtmlFiles)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	limits := crawler.Limits{Milliseconds: 100 * 1000, Requests: 100}
+	strategies := []crawler.Strategy{
+		crawler.NewOneLevel(parsedUrl, crawler.Options{Fetcher: fetcher}),
+		crawler.NewRecursiveParallel(parsedUrl, crawler.Options{Fetcher: fetcher}),
+		crawler.NewRecursiveParallelWithLimits(parsedUrl, crawler.Options{Fetcher: fetcher, Limits: limits}),
+	}
+	for _, strategy := range strategies {
+		assert.Empty(t, strategy.Run(ctx))
+	}
+}
+
+func TestRecursiveParallelWorkers(t *testing.T) {
+	files := NewFileFetcher(t, HtmlFiles)
+
+	// Count the maximum number of concurrent fetches
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	fetcher := crawler.FetcherFunc(func(ctx context.Context, link string) (*http.Response, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return files.Fetch(ctx, link)
+	})
+
+	tests := []struct {
+		workers  int
+		expected int
+	}{
+		{1, 1},
+		{2, 2},
+	}
+
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	limits := crawler.Limits{Milliseconds: 100 * 1000, Requests: 100}
+	for _, tt := range tests {
+		opts := crawler.Options{Fetcher: fetcher, Workers: tt.workers, Limits: limits}
+		strategies := []crawler.Strategy{
+			crawler.NewRecursiveParallel(parsedUrl, opts),
+			crawler.NewRecursiveParallelWithLimits(parsedUrl, opts),
+		}
+		for _, strategy := range strategies {
+			maxRunning = 0
+			result := strategy.Run(context.Background())
+			assert.Equal(t, 7, len(result))
+			assert.Equal(t, tt.expected, maxRunning)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://www.parserdigital.com")
+	tests := []struct {
+		maxDepth int
+		expected int
+	}{
+		{0, 7},
+		{1, 3},
+		{2, 7},
+	}
+
+	for _, tt := range tests {
+		limits := crawler.Limits{Milliseconds: 100 * 1000, Requests: 100, MaxDepth: tt.maxDepth}
+		opts := crawler.Options{Fetcher: NewFileFetcher(t, HtmlFiles), Limits: limits}
+		strategies := []crawler.Strategy{
+			crawler.NewRecursive(parsedUrl, opts),
+			crawler.NewRecursiveWithLimits(parsedUrl, opts),
+			crawler.NewRecursiveParallel(parsedUrl, opts),
+			crawler.NewRecursiveParallelWithLimits(parsedUrl, opts),
+		}
+		for _, strategy := range strategies {
+			result := strategy.Run(context.Background())
+			assert.Equal(t, tt.expected, len(result))
+			for _, page := range result {
+				if tt.maxDepth > 0 {
+					assert.LessOrEqual(t, page.Depth, tt.maxDepth)
+				}
+			}
+		}
+	}
+}
+
+func TestRecursiveShortestDepth(t *testing.T) {
+	// D is two links away from the root through B, and three through A and C
+	pages := map[string]string{
+		"http://www.example.com":   `<a href="/A">A</a><a href="/B">B</a>`,
+		"http://www.example.com/A": `<a href="/C">C</a>`,
+		"http://www.example.com/B": `<a href="/D">D</a>`,
+		"http://www.example.com/C": `<a href="/D">D</a>`,
+		"http://www.example.com/D": `<a href="/E">E</a>`,
+		"http://www.example.com/E": ``,
+	}
+	fetcher := NewPagesFetcher(pages)
+
+	root, _ := url.Parse("http://www.example.com")
+	opts := crawler.Options{Fetcher: fetcher, Limits: crawler.Limits{MaxDepth: 2}}
+	results := crawler.NewRecursive(root, opts).Run(context.Background())
+	assert.ElementsMatch(t, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/B",
+		"http://www.example.com/C", "http://www.example.com/D"}, URLs(results))
+	for _, result := range results {
+		if result.URL == "http://www.example.com/D" {
+			assert.Equal(t, 2, result.Depth)
+			assert.Equal(t, "http://www.example.com/B", result.Parent)
+		}
+	}
+}
+
+func TestBestFirst(t *testing.T) {
+	// D is linked from A and B, while E is linked three times from C only
+	pages := map[string]string{
+		"http://www.example.com":   `<a href="/A">Home</a><a href="/B">Contact</a><a href="/C">Blog</a>`,
+		"http://www.example.com/A": `<a href="/D">D</a>`,
+		"http://www.example.com/B": `<a href="/D">D</a>`,
+		"http://www.example.com/C": `<a href="/E">E</a><a href="/E">E</a><a href="/E">E</a>`,
+		"http://www.example.com/D": ``,
+		"http://www.example.com/E": ``,
+	}
+	fetcher := NewPagesFetcher(pages)
+
+	tests := []struct {
+		name     string
+		scorer   crawler.Scorer
+		requests int
+		expected []string
+	}{
+		{"inbound", nil, 5, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/B",
+			"http://www.example.com/C", "http://www.example.com/D"}},
+		{"keyword", crawler.NewKeywordScorer("contact"), 2, []string{"http://www.example.com", "http://www.example.com/B"}},
+		{"keyword url", crawler.NewKeywordScorer("/a"), 2, []string{"http://www.example.com", "http://www.example.com/A"}},
+	}
+
+	root, _ := url.Parse("http://www.example.com")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := crawler.Options{
+				Fetcher: fetcher,
+				Workers: 1,
+				Scorer:  tt.scorer,
+				Limits:  crawler.Limits{Milliseconds: 100 * 1000, Requests: tt.requests},
+			}
+			urls := URLs(crawler.NewBestFirstWithLimits(root, opts).Run(context.Background()))
+			assert.Equal(t, "http://www.example.com", urls[0])
+			assert.ElementsMatch(t, tt.expected, urls)
+
+			results := crawler.NewBestFirst(root, opts).Run(context.Background())
+			assert.Equal(t, 6, len(results))
+		})
+	}
+}
+
+func TestDepthFirst(t *testing.T) {
+	// Two branches of three pages below the root
+	pages := map[string]string{
+		"http://www.example.com":    `<a href="/A">A</a><a href="/B">B</a>`,
+		"http://www.example.com/A":  `<a href="/A1">A1</a>`,
+		"http://www.example.com/A1": `<a href="/A2">A2</a>`,
+		"http://www.example.com/A2": ``,
+		"http://www.example.com/B":  `<a href="/B1">B1</a>`,
+		"http://www.example.com/B1": `<a href="/B2">B2</a>`,
+		"http://www.example.com/B2": ``,
+	}
+	fetcher := NewPagesFetcher(pages)
+	root, _ := url.Parse("http://www.example.com")
+
+	// The budget is spent on a whole branch, where breadth first stops at depth 1
+	opts := crawler.Options{Fetcher: fetcher, Limits: crawler.Limits{Milliseconds: 100 * 1000, Requests: 4}}
+	urls := URLs(crawler.NewDepthFirstWithLimits(root, opts).Run(context.Background()))
+	assert.Equal(t, 4, len(urls))
+	assert.Equal(t, "http://www.example.com", urls[0])
+	branch := urls[1]
+	assert.Equal(t, []string{branch, branch + "1", branch + "2"}, urls[1:])
+
+	// Every branch is capped
+	opts = crawler.Options{Fetcher: fetcher, Limits: crawler.Limits{BranchPages: 2}}
+	results := crawler.NewDepthFirst(root, opts).Run(context.Background())
+	assert.ElementsMatch(t, []string{"http://www.example.com", "http://www.example.com/A", "http://www.example.com/A1",
+		"http://www.example.com/B", "http://www.example.com/B1"}, URLs(results))
+
+	opts = crawler.Options{Fetcher: fetcher}
+	assert.Equal(t, 7, len(crawler.NewDepthFirst(root, opts).Run(context.Background())))
+
+	// T is found at depth 3 through Q and S when Q is crawled first, and
+	// lowered to depth 2 once found through P
+	pages = map[string]string{
+		"http://www.example.com":   `<a href="/P">P</a><a href="/Q">Q</a>`,
+		"http://www.example.com/P": `<a href="/T">T</a>`,
+		"http://www.example.com/Q": `<a href="/S">S</a>`,
+		"http://www.example.com/S": `<a href="/T">T</a>`,
+		"http://www.example.com/T": `<a href="/U">U</a>`,
+		"http://www.example.com/U": ``,
+	}
+	opts = crawler.Options{Fetcher: NewPagesFetcher(pages), Limits: crawler.Limits{MaxDepth: 3}}
+	depths := map[string]int{}
+	parents := map[string]string{}
+	for _, result := range crawler.NewDepthFirst(root, opts).Run(context.Background()) {
+		depths[result.URL], parents[result.URL] = result.Depth, result.Parent
+	}
+	assert.Equal(t, map[string]int{
+		"http://www.example.com":   0,
+		"http://www.example.com/P": 1,
+		"http://www.example.com/Q": 1,
+		"http://www.example.com/S": 2,
+		"http://www.example.com/T": 2,
+		"http://www.example.com/U": 3,
+	}, depths)
+	assert.Equal(t, "http://www.example.com/P", parents["http://www.example.com/T"])
+	assert.Equal(t, "http://www.example.com/T", parents["http://www.example.com/U"])
+}